@@ -1,19 +1,32 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"flag"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/mykodev/myko/config"
 	pb "github.com/mykodev/myko/proto"
 	"github.com/mykodev/myko/server"
+	"gopkg.in/yaml.v3"
 )
 
-var configFile string
+var (
+	configFile  string
+	printConfig bool
+)
 
 func main() {
 	flag.StringVar(&configFile, "config", "", "")
+	flag.BoolVar(&printConfig, "print-config", false, "print the effective configuration with defaults resolved and secrets redacted and exit")
 	flag.Parse()
 
 	var serverConfig config.Config
@@ -26,13 +39,78 @@ func main() {
 		}
 		serverConfig = cfg
 	}
+	effectiveConfig, err := yaml.Marshal(serverConfig.Resolved().Redacted())
+	if err != nil {
+		log.Fatalf("Failed to encode the config: %v", err)
+	}
+	if printConfig {
+		os.Stdout.Write(effectiveConfig)
+		return
+	}
 
-	service, err := server.New(serverConfig)
+	service, err := server.New(serverConfig, server.WithRegistry(expvar.NewMap("myko")))
 	if err != nil {
 		log.Fatalf("Failed to create a server: %v", err)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var tcpListener net.Listener
+	if serverConfig.TCPListen != "" {
+		l, err := net.Listen("tcp", serverConfig.TCPListen)
+		if err != nil {
+			log.Fatalf("Failed to listen at %q: %v", serverConfig.TCPListen, err)
+		}
+		log.Printf("Accepting binary inserts at %q...", serverConfig.TCPListen)
+		tcpListener = l
+		go func() {
+			err := service.ServeTCP(l, serverConfig.TCPMaxConns, serverConfig.TCPIdleTimeout)
+			if !errors.Is(err, net.ErrClosed) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
 	log.Printf("Starting the myko server at %q...", serverConfig.Listen)
-	server := pb.NewServiceServer(service, nil)
-	log.Fatal(http.ListenAndServe(serverConfig.Listen, server))
+	handler := pb.NewServiceServer(service, nil)
+
+	tenantHandler, err := server.WithTenant(server.WithHeaders(handler), serverConfig.TenantConfig)
+	if err != nil {
+		log.Fatalf("Failed to configure tenants: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(handler.PathPrefix(), service.LimitRequests(tenantHandler))
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(effectiveConfig)
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !service.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	httpServer := &http.Server{Addr: serverConfig.Listen, Handler: mux}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down the HTTP server: %v", err)
+	}
+	if tcpListener != nil {
+		tcpListener.Close()
+	}
+	// Inserts the queued async requests and flushes the buffer.
+	if err := service.Close(); err != nil {
+		log.Fatalf("Failed to flush the buffered events: %v", err)
+	}
 }
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"sort"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -10,14 +14,52 @@ import (
 type Config struct {
 	Listen string `yaml:"listen"`
 
+	// MaxInFlight is the uppermost number of requests served
+	// concurrently at Listen and TCPListen. Zero means no limit.
+	MaxInFlight int `yaml:"max_in_flight"`
+
+	// MaxQueued is the uppermost number of requests waiting
+	// for one of the MaxInFlight slots. Excess requests are
+	// rejected.
+	MaxQueued int `yaml:"max_queued"`
+
+	// TCPListen is the address to accept inserts in the
+	// binary ingestion protocol. Empty disables it.
+	TCPListen string `yaml:"tcp_listen"`
+
+	// TCPMaxConns is the uppermost number of concurrent binary
+	// ingestion connections. Zero means no limit.
+	TCPMaxConns int `yaml:"tcp_max_conns"`
+
+	// TCPIdleTimeout closes the binary ingestion connections
+	// no frame arrives at within it. Zero means no timeout.
+	TCPIdleTimeout time.Duration `yaml:"tcp_idle_timeout"`
+
 	DataConfig DataConfig `yaml:"data"`
 
 	FlushConfig FlushConfig `yaml:"flush"`
+
+	QueryConfig QueryConfig `yaml:"query"`
+
+	IngestConfig IngestConfig `yaml:"ingest"`
+
+	DebugConfig DebugConfig `yaml:"debug"`
+
+	ExpiryConfig ExpiryConfig `yaml:"expiry"`
+
+	DeleteConfig DeleteConfig `yaml:"delete"`
+
+	TenantConfig TenantConfig `yaml:"tenant"`
+
+	// SafeMode disables all operations that remove data,
+	// e.g. DeleteEvents. It is useful for append-only deployments.
+	SafeMode bool `yaml:"safe_mode"`
 }
 
 func DefaultConfig() Config {
 	return Config{
-		Listen: ":6959",
+		Listen:         ":6959",
+		TCPIdleTimeout: 5 * time.Minute,
 		DataConfig: DataConfig{
 			CassandraConfig: CassandraConfig{
 				Keyspace: "myko",
@@ -27,8 +69,24 @@ func DefaultConfig() Config {
 			},
 		},
 		FlushConfig: FlushConfig{
-			BufferSize: 1000,
-			Interval:   5 * time.Second,
+			BufferSize:   1000,
+			Interval:     5 * time.Second,
+			Retries:      3,
+			RetryBackoff: 100 * time.Millisecond,
+		},
+		QueryConfig: QueryConfig{
+			RetryBackoff:   100 * time.Millisecond,
+			MaxTraceEvents: 10000,
+		},
+		IngestConfig: IngestConfig{
+			AsyncWorkers:   4,
+			AsyncQueueSize: 1000,
+		},
+		DeleteConfig: DeleteConfig{
+			BatchSize:      100,
+			ExpireTTL:      time.Minute,
+			MaxReplaceRows: 100,
+			MaxIDs:         1000,
 		},
 	}
 }
@@ -48,9 +106,34 @@ type CassandraConfig struct {
 
 	Datacenter string `yaml:"dc,omitempty"`
 
+	// AnalyticsDatacenter is the datacenter queries are routed
+	// to, so they don't load the nodes serving ingestion. Empty
+	// routes queries like writes.
+	AnalyticsDatacenter string `yaml:"analytics_dc,omitempty"`
+
+	// AnalyticsConsistency is the consistency level of the
+	// queries routed to AnalyticsDatacenter, e.g. "LOCAL_ONE".
+	// Empty uses the same level as writes.
+	AnalyticsConsistency string `yaml:"analytics_consistency,omitempty"`
+
 	Timeout time.Duration `yaml:"timeout,omitempty"`
 
 	TTL time.Duration `yaml:"ttl"`
+
+	// EventTTLs overrides TTL for the event names matching
+	// the patterns, in path.Match syntax. An exact name is
+	// preferred over patterns, longer patterns over shorter
+	// ones.
+	EventTTLs map[string]time.Duration `yaml:"event_ttls"`
+
+	// WarmUp prepares the statements run by the server on
+	// each session before it reports itself as ready.
+	WarmUp bool `yaml:"warm_up"`
+
+	// SchemaCheckInterval is how often the events table is
+	// checked for drift from the expected schema after
+	// startup. Zero disables the periodic check.
+	SchemaCheckInterval time.Duration `yaml:"schema_check_interval"`
 }
 
 type FlushConfig struct {
@@ -61,6 +144,343 @@ type FlushConfig struct {
 	// Interval is the uppermost duration to wait before
 	// all in-memory data points are flushed out to the datastore.
 	Interval time.Duration `yaml:"interval"`
+
+	// MinInterval is the lowermost duration between two flushes.
+	// Flushes triggered earlier, e.g. due to a full buffer, are
+	// delayed and coalesced into the next flush.
+	MinInterval time.Duration `yaml:"min_interval"`
+
+	// BucketSize optionally buffers events by the time bucket
+	// they are written in, events from different buckets are
+	// flushed as separate data points created at the start of
+	// their bucket.
+	BucketSize time.Duration `yaml:"bucket_size"`
+
+	// Retries is the number of times a failed flush is retried
+	// if the datastore returns a transient error.
+	Retries int `yaml:"retries"`
+
+	// RetryBackoff is the wait before the first retry. It
+	// doubles with each following retry.
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+
+	// Order is the order buffered rows are added to a flush
+	// batch in. "key" sorts them by their grouping attributes,
+	// "shuffle" randomizes them. Empty keeps the buffer order.
+	Order string `yaml:"order"`
+
+	// MergeWindow optionally merges the data points flushed
+	// again within the window into the row they were last
+	// written to, rather than writing a new row. Each merge
+	// is a lightweight transaction, rows removed since are
+	// written again as new rows. Zero disables merging.
+	MergeWindow time.Duration `yaml:"merge_window"`
+}
+
+type QueryConfig struct {
+	// EventAliases maps old event names to new ones.
+	// Events stored under an old name are reported
+	// under the new name when querying.
+	EventAliases map[string]string `yaml:"event_aliases"`
+
+	// Parallelism is the number of token ranges scanned
+	// concurrently by a query. Values less than two
+	// scan the table sequentially.
+	Parallelism int `yaml:"parallelism"`
+
+	// LegacyKeys enables reading events written before colons
+	// were allowed in event attributes, when colons were replaced
+	// with underscores. It should be enabled until all legacy
+	// rows are expired.
+	LegacyKeys bool `yaml:"legacy_keys"`
+
+	// MaxTraceEvents is the uppermost number of rows QueryTrace
+	// returns, traces with more rows are refused. Zero means
+	// no limit.
+	MaxTraceEvents int `yaml:"max_trace_events"`
+
+	// Units is the registry of units values can be
+	// converted between when querying.
+	Units map[string]Unit `yaml:"units"`
+
+	// FanOutKeyspaces are the keyspaces a query may be
+	// run across in addition to the configured one.
+	FanOutKeyspaces []string `yaml:"fan_out_keyspaces"`
+
+	// FanOutParallelism is the number of keyspaces queried
+	// concurrently by a fan-out query. Values less than one
+	// query them sequentially.
+	FanOutParallelism int `yaml:"fan_out_parallelism"`
+
+	// SlowQueryThreshold is the latency above which queries
+	// are logged with their filter and stats. Zero disables
+	// slow query logging.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+
+	// SlowQuerySampleRate is the fraction of the slow queries
+	// that are logged, in (0, 1]. Zero logs all of them.
+	SlowQuerySampleRate float64 `yaml:"slow_query_sample_rate"`
+
+	// DowngradeConsistency retries queries one consistency level
+	// weaker, e.g. QUORUM to ONE, if there are not enough replicas.
+	// Responses of downgraded queries are flagged, since they may
+	// miss the latest writes.
+	DowngradeConsistency bool `yaml:"downgrade_consistency"`
+
+	// Retries is the number of times a failed scan is retried
+	// if the datastore returns a transient error. Retries give
+	// up early if the request deadline would pass.
+	Retries int `yaml:"retries"`
+
+	// RetryBackoff is the wait before the first retry. It
+	// doubles with each following retry.
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+}
+
+// Unit describes a unit in terms of its dimension,
+// e.g. millisecond is a time unit with a factor of 0.001.
+type Unit struct {
+	// Dimension is the measured quantity, e.g. "time" or "bytes".
+	// Values can only be converted between units of the same dimension.
+	Dimension string `yaml:"dimension"`
+
+	// Factor is the multiplier converting values in the unit
+	// to the base unit of the dimension, a finite positive number.
+	Factor float64 `yaml:"factor"`
+}
+
+type IngestConfig struct {
+	// MaxUnitsPerEvent is the uppermost number of distinct
+	// units an event name can be recorded with. Zero disables
+	// the limit.
+	MaxUnitsPerEvent int `yaml:"max_units_per_event"`
+
+	// RejectUnitOverflow rejects the events exceeding
+	// MaxUnitsPerEvent. Otherwise, they are recorded
+	// with the "unknown" unit.
+	RejectUnitOverflow bool `yaml:"reject_unit_overflow"`
+
+	// DefaultUnits maps origins to event names to the unit
+	// used when an event is inserted without a unit.
+	DefaultUnits map[string]map[string]string `yaml:"default_units"`
+
+	// RequireUnits rejects events without a unit
+	// if there is no default unit for them.
+	RequireUnits bool `yaml:"require_units"`
+
+	// MaxAbsValue is the uppermost magnitude of an inserted
+	// value. Zero disables the limit, NaN and infinite values
+	// are rejected either way.
+	MaxAbsValue float64 `yaml:"max_abs_value"`
+
+	// ClampValues clamps the values exceeding MaxAbsValue to
+	// it. Otherwise, the events are rejected.
+	ClampValues bool `yaml:"clamp_values"`
+
+	// Catalog declares the expected events by name. It is
+	// only enforced if CatalogMode is set.
+	Catalog map[string]CatalogEvent `yaml:"catalog"`
+
+	// CatalogMode is how events not conforming to Catalog are
+	// handled. "strict" rejects them, "lenient" logs and counts
+	// them but accepts them. Empty disables the catalog.
+	CatalogMode string `yaml:"catalog_mode"`
+
+	// Pipeline is the order of the ingestion stages inserted
+	// entries are run through: "default_units", "catalog",
+	// "unit_limit", "value_limit", "sample" and the custom
+	// stages of the server. Stages left out don't run. Empty
+	// runs the built-in stages in that order, then the custom
+	// ones. Names are always normalized first.
+	Pipeline []string `yaml:"pipeline"`
+
+	// SampleRate is the fraction of the entries kept by the
+	// "sample" stage, in [0, 1]. The entries of a trace are
+	// kept or dropped together. Sums only cover the kept
+	// entries. Zero keeps all of them.
+	SampleRate float64 `yaml:"sample_rate"`
+
+	// NameNormalization is applied to the inserted
+	// event names. It is disabled by default.
+	NameNormalization NameNormalization `yaml:"name_normalization"`
+
+	// AsyncWorkers is the number of workers inserting the
+	// events of the requests written in the "async" mode.
+	AsyncWorkers int `yaml:"async_workers"`
+
+	// AsyncQueueSize is the uppermost number of "async" requests
+	// waiting for a worker. Requests above it are rejected with a
+	// ResourceExhausted error.
+	AsyncQueueSize int `yaml:"async_queue_size"`
+}
+
+type CatalogEvent struct {
+	// Unit is the expected unit of the event.
+	Unit string `yaml:"unit"`
+
+	// Type is the expected type of the value, "int" for
+	// integer values or "float" for floating point values.
+	// Empty accepts both.
+	Type string `yaml:"type"`
+}
+
+type NameNormalization struct {
+	// Trim removes the leading and trailing white space.
+	Trim bool `yaml:"trim"`
+
+	// Lowercase maps the names to lower case.
+	Lowercase bool `yaml:"lowercase"`
+
+	// Separators are the characters replaced by Separator,
+	// e.g. "/-" with "." turns "http/requests" into
+	// "http.requests". Consecutive separators are collapsed
+	// into one, leading and trailing ones are removed. Empty
+	// disables separator canonicalization. Separator must be
+	// set if Separators is.
+	Separators string `yaml:"separators"`
+	Separator  string `yaml:"separator"`
+}
+
+type DeleteConfig struct {
+	// Strategy is how DeleteEvents removes the rows.
+	//
+	// "immediate", the default, deletes each row with its own
+	// statement. Rows disappear at once, but each delete leaves
+	// a tombstone that reads skip until it is compacted away.
+	//
+	// "batch" deletes the rows in unlogged batches of BatchSize.
+	// It leaves the same tombstones but saves round trips, at
+	// the cost of loading the coordinator.
+	//
+	// "expire" re-writes the rows with ExpireTTL. The rows stay
+	// visible until they expire and still become tombstones
+	// then, but no tombstone is written while they are read.
+	// Rows expiring sooner than ExpireTTL are left as they are.
+	Strategy string `yaml:"strategy"`
+
+	// BatchSize is the number of rows deleted per batch
+	// by the "batch" strategy.
+	BatchSize int `yaml:"batch_size"`
+
+	// ExpireTTL is the TTL rows are re-written with
+	// by the "expire" strategy.
+	ExpireTTL time.Duration `yaml:"expire_ttl"`
+
+	// MaxScan is the uppermost number of rows a delete filter
+	// may match. Deletes matching more are refused before any
+	// row is removed. Zero means no limit.
+	MaxScan int `yaml:"max_scan"`
+
+	// MaxReplaceRows is the uppermost number of rows a
+	// ReplaceEvents call may remove and write together in its
+	// logged batch. Larger replacements are refused. Zero
+	// means no limit.
+	MaxReplaceRows int `yaml:"max_replace_rows"`
+
+	// MaxIDs is the uppermost number of ids a delete request
+	// may list. Zero means no limit.
+	MaxIDs int `yaml:"max_ids"`
+}
+
+type TenantConfig struct {
+	// Source is where the tenant of a request is read from.
+	// "header" reads it from Header, "api_key" maps the key
+	// in Header to a tenant with APIKeys. Empty disables
+	// tenant extraction.
+	Source string `yaml:"source"`
+
+	// Header is the request header carrying the tenant or
+	// the API key.
+	Header string `yaml:"header"`
+
+	// APIKeys maps API keys to tenants.
+	APIKeys map[string]string `yaml:"api_keys"`
+
+	// Required rejects the requests without a tenant.
+	Required bool `yaml:"required"`
+}
+
+type ExpiryConfig struct {
+	// CheckInterval is how often origins are checked for
+	// expiry, i.e. whether all of their events are expired.
+	// Zero disables expiry checks.
+	CheckInterval time.Duration `yaml:"check_interval"`
+
+	// WebhookURL is optionally sent a POST request with
+	// a JSON body like {"origin": "..."} when an origin expires.
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+type DebugConfig struct {
+	// RecentEvents is the number of most recently ingested
+	// entries kept in-memory for debugging. Zero disables it.
+	RecentEvents int `yaml:"recent_events"`
+
+	// SnapshotDir is the directory SnapshotBuffer writes
+	// the buffered events to. Empty disables snapshots.
+	SnapshotDir string `yaml:"snapshot_dir"`
+}
+
+// Redacted returns a copy of the config with the secrets redacted,
+// so it can be displayed or logged.
+func (c Config) Redacted() Config {
+	if c.DataConfig.CassandraConfig.Password != "" {
+		c.DataConfig.CassandraConfig.Password = "REDACTED"
+	}
+	if len(c.TenantConfig.APIKeys) > 0 {
+		keys := make(map[string]string, len(c.TenantConfig.APIKeys))
+		for i, tenant := range sortedValues(c.TenantConfig.APIKeys) {
+			keys[fmt.Sprintf("REDACTED-%d", i)] = tenant
+		}
+		c.TenantConfig.APIKeys = keys
+	}
+	if u := c.ExpiryConfig.WebhookURL; u != "" {
+		// Webhook URLs commonly carry a token in
+		// their path or query, only the host is kept.
+		c.ExpiryConfig.WebhookURL = "REDACTED"
+		if parsed, err := url.Parse(u); err == nil && parsed.Host != "" {
+			c.ExpiryConfig.WebhookURL = parsed.Scheme + "://" + parsed.Host + "/REDACTED"
+		}
+	}
+	return c
+}
+
+// Validate returns an error if the config can't be run,
+// e.g. a unit factor that would make conversions NaN.
+func (c Config) Validate() error {
+	names := make([]string, 0, len(c.QueryConfig.Units))
+	for name := range c.QueryConfig.Units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		u := c.QueryConfig.Units[name]
+		if u.Dimension == "" {
+			return fmt.Errorf("unit %q has no dimension", name)
+		}
+		if !(u.Factor > 0) || math.IsInf(u.Factor, 0) {
+			return fmt.Errorf("unit %q has factor %v, want a finite positive number", name, u.Factor)
+		}
+	}
+	return nil
+}
+
+// Resolved returns a copy of the config with the defaults
+// of the fields left empty filled in, as the server runs it.
+func (c Config) Resolved() Config {
+	if c.DeleteConfig.Strategy == "" {
+		c.DeleteConfig.Strategy = "immediate"
+	}
+	return c
+}
+
+func sortedValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
 }
 
 func Open(path string) (Config, error) {
@@ -0,0 +1,21 @@
+package cassandra
+
+import "github.com/gocql/gocql"
+
+// Downgrade returns the consistency level one step weaker
+// than c, false if there is none.
+func Downgrade(c gocql.Consistency) (gocql.Consistency, bool) {
+	switch c {
+	case gocql.All:
+		return gocql.Quorum, true
+	case gocql.EachQuorum:
+		return gocql.LocalQuorum, true
+	case gocql.Quorum, gocql.Two:
+		return gocql.One, true
+	case gocql.Three:
+		return gocql.Two, true
+	case gocql.LocalQuorum:
+		return gocql.LocalOne, true
+	}
+	return c, false
+}
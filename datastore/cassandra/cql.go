@@ -4,30 +4,141 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type Filter struct {
 	TraceID string
 	Origin  string
 	Event   string
+
+	// Start and End optionally limit the filter to the
+	// events created in [Start, End).
+	Start time.Time
+	End   time.Time
 }
 
-func (f Filter) CQL() (string, error) {
+// Validate returns an error if the filter matches no
+// events or would match all of them.
+func (f Filter) Validate() error {
 	if f.TraceID == "" && f.Origin == "" && f.Event == "" {
-		return "", errors.New("no trace_id, origin or event")
+		return errors.New("no trace_id, origin or event")
+	}
+	if !f.Start.IsZero() && !f.End.IsZero() && !f.Start.Before(f.End) {
+		return errors.New("start time is not before end time")
+	}
+	return nil
+}
+
+// CQL returns the WHERE clause of the filter
+// and the values bound to its placeholders.
+func (f Filter) CQL() (string, []interface{}, error) {
+	if err := f.Validate(); err != nil {
+		return "", nil, err
 	}
 
-	// TODO: Escape the input.
-	var filters []string
+	var (
+		filters []string
+		vals    []interface{}
+	)
 	if f.TraceID != "" {
-		filters = append(filters, fmt.Sprintf("trace_id = '%v'", f.TraceID))
+		filters = append(filters, "trace_id = ?")
+		vals = append(vals, f.TraceID)
 	}
 	if f.Origin != "" {
-		filters = append(filters, fmt.Sprintf("origin = '%v'", f.Origin))
+		filters = append(filters, "origin = ?")
+		vals = append(vals, f.Origin)
 	}
 	if f.Event != "" {
-		filters = append(filters, fmt.Sprintf("event = '%v'", f.Event))
+		filters = append(filters, "event = ?")
+		vals = append(vals, f.Event)
+	}
+	if !f.Start.IsZero() {
+		filters = append(filters, "created_at >= ?")
+		vals = append(vals, f.Start)
+	}
+	if !f.End.IsZero() {
+		filters = append(filters, "created_at < ?")
+		vals = append(vals, f.End)
+	}
+
+	return "WHERE " + strings.Join(filters, " AND "), vals, nil
+}
+
+// NeedsFiltering reports whether querying with the filter
+// requires ALLOW FILTERING, i.e. it can't be served by a
+// single secondary index lookup and may scan the table.
+func (f Filter) NeedsFiltering() bool {
+	var n int
+	for _, v := range []string{f.TraceID, f.Origin, f.Event} {
+		if v != "" {
+			n++
+		}
 	}
+	return n > 1 || !f.Start.IsZero() || !f.End.IsZero()
+}
+
+// FilterBuilder builds a filter, reporting the invalid
+// combinations of restrictions before any CQL is generated.
+type FilterBuilder struct {
+	f   Filter
+	err error
+}
+
+func NewFilter() *FilterBuilder {
+	return &FilterBuilder{}
+}
 
-	return "WHERE " + strings.Join(filters, " AND "), nil
+// WithTraceID restricts the filter to the trace.
+// Empty values are ignored.
+func (b *FilterBuilder) WithTraceID(traceID string) *FilterBuilder {
+	b.set("trace_id", &b.f.TraceID, traceID)
+	return b
+}
+
+// WithOrigin restricts the filter to the origin.
+// Empty values are ignored.
+func (b *FilterBuilder) WithOrigin(origin string) *FilterBuilder {
+	b.set("origin", &b.f.Origin, origin)
+	return b
+}
+
+// WithEvent restricts the filter to the event name.
+// Empty values are ignored.
+func (b *FilterBuilder) WithEvent(event string) *FilterBuilder {
+	b.set("event", &b.f.Event, event)
+	return b
+}
+
+// WithTimeRange restricts the filter to the events created
+// in [start, end). Either of them may be zero to leave that
+// side of the range open.
+func (b *FilterBuilder) WithTimeRange(start, end time.Time) *FilterBuilder {
+	if b.err == nil && (!b.f.Start.IsZero() || !b.f.End.IsZero()) {
+		b.err = errors.New("time range is set more than once")
+	}
+	b.f.Start, b.f.End = start, end
+	return b
+}
+
+func (b *FilterBuilder) set(name string, dst *string, v string) {
+	if v == "" {
+		return
+	}
+	if b.err == nil && *dst != "" && *dst != v {
+		b.err = fmt.Errorf("conflicting %s values %q and %q", name, *dst, v)
+	}
+	*dst = v
+}
+
+// Build returns the filter or the first error
+// found while building it.
+func (b *FilterBuilder) Build() (Filter, error) {
+	if b.err != nil {
+		return Filter{}, b.err
+	}
+	if err := b.f.Validate(); err != nil {
+		return Filter{}, err
+	}
+	return b.f, nil
 }
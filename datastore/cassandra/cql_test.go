@@ -0,0 +1,99 @@
+package cassandra
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	var (
+		t1 = time.Unix(100, 0)
+		t2 = time.Unix(200, 0)
+	)
+	tests := []struct {
+		name    string
+		build   func(b *FilterBuilder) *FilterBuilder
+		want    Filter
+		wantErr bool
+	}{
+		{
+			name:  "filter",
+			build: func(b *FilterBuilder) *FilterBuilder { return b.WithOrigin("o").WithEvent("e").WithTimeRange(t1, t2) },
+			want:  Filter{Origin: "o", Event: "e", Start: t1, End: t2},
+		},
+		{
+			name:  "open range",
+			build: func(b *FilterBuilder) *FilterBuilder { return b.WithTraceID("t").WithTimeRange(time.Time{}, t2) },
+			want:  Filter{TraceID: "t", End: t2},
+		},
+		{
+			name:  "empty values ignored",
+			build: func(b *FilterBuilder) *FilterBuilder { return b.WithOrigin("o").WithOrigin("").WithTraceID("") },
+			want:  Filter{Origin: "o"},
+		},
+		{
+			name:  "repeated value",
+			build: func(b *FilterBuilder) *FilterBuilder { return b.WithEvent("e").WithEvent("e") },
+			want:  Filter{Event: "e"},
+		},
+		{
+			name:    "empty",
+			build:   func(b *FilterBuilder) *FilterBuilder { return b.WithOrigin("").WithTimeRange(t1, t2) },
+			wantErr: true,
+		},
+		{
+			name:    "inverted range",
+			build:   func(b *FilterBuilder) *FilterBuilder { return b.WithOrigin("o").WithTimeRange(t2, t1) },
+			wantErr: true,
+		},
+		{
+			name:    "equal bounds",
+			build:   func(b *FilterBuilder) *FilterBuilder { return b.WithOrigin("o").WithTimeRange(t1, t1) },
+			wantErr: true,
+		},
+		{
+			name:    "conflicting values",
+			build:   func(b *FilterBuilder) *FilterBuilder { return b.WithOrigin("a").WithOrigin("b") },
+			wantErr: true,
+		},
+		{
+			name: "time range set twice",
+			build: func(b *FilterBuilder) *FilterBuilder {
+				return b.WithOrigin("o").WithTimeRange(t1, t2).WithTimeRange(t1, t2)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.build(NewFilter()).Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCQL(t *testing.T) {
+	f := Filter{TraceID: "t", Event: "e", Start: time.Unix(100, 0)}
+	where, vals, err := f.CQL()
+	if err != nil {
+		t.Fatalf("CQL() = %v", err)
+	}
+	if want := "WHERE trace_id = ? AND event = ? AND created_at >= ?"; where != want {
+		t.Errorf("CQL() = %q, want %q", where, want)
+	}
+	if want := []interface{}{"t", "e", f.Start}; !reflect.DeepEqual(vals, want) {
+		t.Errorf("CQL() values = %v, want %v", vals, want)
+	}
+	if !f.NeedsFiltering() {
+		t.Error("NeedsFiltering() = false, want true")
+	}
+	if (Filter{Origin: "o"}).NeedsFiltering() {
+		t.Error("NeedsFiltering() of a single column = true, want false")
+	}
+}
@@ -0,0 +1,61 @@
+package cassandra
+
+import (
+	"errors"
+
+	"github.com/gocql/gocql"
+)
+
+// IsRetryable reports whether err is a transient error
+// and the failed operation can be retried. Errors such as
+// syntax errors or authorization failures are fatal and
+// are never retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var reqErr gocql.RequestError
+	if errors.As(err, &reqErr) {
+		switch reqErr.Code() {
+		case gocql.ErrCodeUnavailable,
+			gocql.ErrCodeOverloaded,
+			gocql.ErrCodeBootstrapping,
+			gocql.ErrCodeWriteTimeout,
+			gocql.ErrCodeReadTimeout:
+			return true
+		}
+		return false
+	}
+	for _, e := range []error{
+		gocql.ErrTimeoutNoResponse,
+		gocql.ErrTooManyTimeouts,
+		gocql.ErrConnectionClosed,
+		gocql.ErrNoStreams,
+		gocql.ErrNoConnections,
+		gocql.ErrUnavailable,
+	} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnavailable reports whether err is returned because
+// there were not enough replicas to satisfy the consistency level.
+func IsUnavailable(err error) bool {
+	var reqErr gocql.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.Code() == gocql.ErrCodeUnavailable
+	}
+	return false
+}
+
+// IsSchemaDrift reports whether err is returned because the
+// events table differs from the expected schema, rather than
+// because it couldn't be checked.
+func IsSchemaDrift(err error) bool {
+	return errors.Is(err, ErrMissingTable) ||
+		errors.Is(err, ErrMissingColumn) ||
+		errors.Is(err, ErrColumnType)
+}
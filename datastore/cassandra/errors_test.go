@@ -0,0 +1,53 @@
+package cassandra
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+// requestError is an error returned by Cassandra with the code.
+type requestError int
+
+func (e requestError) Code() int       { return int(e) }
+func (e requestError) Message() string { return fmt.Sprintf("error %#x", int(e)) }
+func (e requestError) Error() string   { return e.Message() }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil},
+		{name: "syntax", err: requestError(gocql.ErrCodeSyntax)},
+		{name: "unauthorized", err: requestError(gocql.ErrCodeUnauthorized)},
+		{name: "invalid", err: requestError(gocql.ErrCodeInvalid)},
+		{name: "read timeout", err: requestError(gocql.ErrCodeReadTimeout), want: true},
+		{name: "write timeout", err: requestError(gocql.ErrCodeWriteTimeout), want: true},
+		{name: "unavailable", err: requestError(gocql.ErrCodeUnavailable), want: true},
+		{name: "overloaded", err: requestError(gocql.ErrCodeOverloaded), want: true},
+		{name: "wrapped", err: fmt.Errorf("scan: %w", requestError(gocql.ErrCodeUnavailable)), want: true},
+		{name: "no response", err: gocql.ErrTimeoutNoResponse, want: true},
+		{name: "no connections", err: gocql.ErrNoConnections, want: true},
+		{name: "other", err: errors.New("failed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	if !IsUnavailable(requestError(gocql.ErrCodeUnavailable)) {
+		t.Error("IsUnavailable(unavailable) = false, want true")
+	}
+	if IsUnavailable(requestError(gocql.ErrCodeReadTimeout)) {
+		t.Error("IsUnavailable(read timeout) = true, want false")
+	}
+}
@@ -2,21 +2,56 @@ package cassandra
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
+	"strings"
+	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/mykodev/myko/config"
+	"github.com/mykodev/myko/datastore"
 )
 
 type Session struct {
-	ttl      int64
-	keyspace string
-	session  *gocql.Session
+	ttl         int64
+	keyspace    string
+	consistency gocql.Consistency
+	session     *gocql.Session
 }
 
 func NewSession(c config.CassandraConfig) (*Session, error) {
+	s, err := newSession(c, c.Datacenter, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range initCQLs {
+		query, err := s.Query(q)
+		if err != nil {
+			return nil, fmt.Errorf("failed create query for %q: %v", q, err)
+		}
+		if err = query.Exec(); err != nil {
+			return nil, fmt.Errorf("failed to run %q: %v", q, err)
+		}
+	}
+	if err := s.checkColumns(); err != nil {
+		return nil, fmt.Errorf("unexpected events table schema: %v", err)
+	}
+	return s, nil
+}
+
+// NewAnalyticsSession returns a session for the queries routed to
+// the analytics datacenter, so they don't load the nodes serving
+// ingestion. It expects the keyspace to be created by NewSession.
+func NewAnalyticsSession(c config.CassandraConfig) (*Session, error) {
+	if c.AnalyticsDatacenter == "" {
+		return nil, errors.New("no analytics datacenter given")
+	}
+	return newSession(c, c.AnalyticsDatacenter, c.AnalyticsConsistency)
+}
+
+func newSession(c config.CassandraConfig, dc, consistency string) (*Session, error) {
 	if len(c.Peers) == 0 {
 		return nil, errors.New("no peers given")
 	}
@@ -25,14 +60,21 @@ func NewSession(c config.CassandraConfig) (*Session, error) {
 	if c.Username != "" {
 		cluster.Authenticator = gocql.PasswordAuthenticator{Username: c.Username, Password: c.Password}
 	}
-	if c.Datacenter != "" {
-		cluster.PoolConfig.HostSelectionPolicy = gocql.DCAwareRoundRobinPolicy(c.Datacenter)
+	if dc != "" {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.DCAwareRoundRobinPolicy(dc)
 	}
 	cluster.ProtoVersion = 4
 
-	if len(c.Peers) == 1 {
+	switch {
+	case consistency != "":
+		cl, err := gocql.ParseConsistencyWrapper(consistency)
+		if err != nil {
+			return nil, err
+		}
+		cluster.Consistency = cl
+	case len(c.Peers) == 1:
 		cluster.Consistency = gocql.LocalOne
-	} else {
+	default:
 		cluster.Consistency = gocql.Quorum
 	}
 
@@ -40,43 +82,205 @@ func NewSession(c config.CassandraConfig) (*Session, error) {
 	if err != nil {
 		return nil, err
 	}
+	return &Session{
+		ttl:         int64(c.TTL / time.Second),
+		keyspace:    c.Keyspace,
+		consistency: cluster.Consistency,
+		session:     session,
+	}, nil
+}
 
-	s := &Session{
-		ttl:      int64(c.TTL) / (1000 * 1000), // in seconds
-		keyspace: c.Keyspace,
-		session:  session,
+// checkColumns checks that the events table has each of the
+// columns myko reads and writes with the expected type, adding
+// the ones missing from a table created by an older version of
+// myko. Other columns are ignored, statements always name the
+// columns they bind to.
+func (s *Session) checkColumns() error {
+	table, err := s.eventsTable()
+	if err != nil {
+		return err
 	}
-	for _, q := range initCQLs {
-		query, err := s.Query(q)
+	for _, c := range Columns {
+		err := checkColumn(table, c)
+		if errors.Is(err, ErrMissingColumn) && isAdded(c.Name) {
+			q, err := s.Query(`ALTER TABLE {{.Keyspace}}.events ADD ` + c.Name + ` ` + c.CQLType)
+			if err != nil {
+				return err
+			}
+			if err := q.Exec(); err != nil {
+				return fmt.Errorf("failed to add column %q: %v", c.Name, err)
+			}
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed create query for %q: %v", q, err)
+			return err
 		}
-		if err = query.Exec(); err != nil {
-			return nil, fmt.Errorf("failed to run %q: %v", q, err)
+	}
+	return nil
+}
+
+// CheckSchema reports whether the live events table has drifted
+// from the expected one, e.g. if it was altered manually. Unlike
+// the startup check, it doesn't alter the table. Errors other
+// than drifts, see IsSchemaDrift, mean the table couldn't be
+// checked.
+func (s *Session) CheckSchema() error {
+	table, err := s.eventsTable()
+	if err != nil {
+		return err
+	}
+	for _, c := range Columns {
+		if err := checkColumn(table, c); err != nil {
+			return err
 		}
 	}
-	return s, nil
+	return nil
 }
 
-func (s *Session) Query(q string, vals ...interface{}) (*gocql.Query, error) {
-	tmpl, err := template.New(q).Parse(q)
+func (s *Session) eventsTable() (*gocql.TableMetadata, error) {
+	md, err := s.session.KeyspaceMetadata(s.keyspace)
+	if err != nil {
+		return nil, err
+	}
+	table, ok := md.Tables["events"]
+	if !ok {
+		return nil, ErrMissingTable
+	}
+	return table, nil
+}
+
+// The errors reporting that the events table drifted
+// from the expected schema, see IsSchemaDrift.
+var (
+	ErrMissingTable  = errors.New("no events table")
+	ErrMissingColumn = errors.New("missing column")
+	ErrColumnType    = errors.New("unexpected column type")
+)
+
+func checkColumn(table *gocql.TableMetadata, c Column) error {
+	col, ok := table.Columns[c.Name]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrMissingColumn, c.Name)
+	}
+	if typ := canonicalType(fmt.Sprint(col.Type)); typ != c.CQLType {
+		return fmt.Errorf("%w of %q: %s, want %s", ErrColumnType, c.Name, typ, c.CQLType)
+	}
+	return nil
+}
+
+func isAdded(name string) bool {
+	for _, c := range addedColumns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func canonicalType(t string) string {
+	t = strings.ToLower(t)
+	if t == "varchar" {
+		return "text"
+	}
+	return t
+}
+
+func (s *Session) Query(q string, vals ...interface{}) (datastore.Query, error) {
+	stmt, err := s.statement(q)
 	if err != nil {
 		return nil, err
 	}
+	return &query{q: s.session.Query(stmt, vals...)}, nil
+}
+
+// statement executes the statement template.
+func (s *Session) statement(q string) (string, error) {
+	tmpl, err := template.New(q).Parse(q)
+	if err != nil {
+		return "", err
+	}
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, &queryData{
 		Keyspace: s.keyspace,
 		TTL:      s.ttl,
 	}); err != nil {
-		return nil, err
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// query adapts a gocql query to datastore.Query.
+type query struct {
+	q *gocql.Query
+}
+
+func (q *query) Consistency(c gocql.Consistency) datastore.Query {
+	q.q.Consistency(c)
+	return q
+}
+
+func (q *query) WithContext(ctx context.Context) datastore.Query {
+	return &query{q: q.q.WithContext(ctx)}
+}
+
+func (q *query) Exec() error {
+	return q.q.Exec()
+}
+
+func (q *query) Scan(dest ...interface{}) error {
+	return q.q.Scan(dest...)
+}
+
+func (q *query) ScanCAS(dest ...interface{}) (bool, error) {
+	return q.q.ScanCAS(dest...)
+}
+
+func (q *query) Iter() datastore.Iter {
+	return q.q.Iter()
+}
+
+// InKeyspace returns a session sharing the connections
+// of s that runs the queries in the given keyspace.
+func (s *Session) InKeyspace(keyspace string) datastore.Session {
+	return &Session{
+		ttl:         s.ttl,
+		keyspace:    keyspace,
+		consistency: s.consistency,
+		session:     s.session,
+	}
+}
+
+// Consistency returns the default consistency level of the queries.
+func (s *Session) Consistency() gocql.Consistency {
+	return s.consistency
+}
+
+// errPrepared stops the statements run by Prepare
+// once they are prepared.
+var errPrepared = errors.New("prepared")
+
+// Prepare prepares the statement without running it. gocql has no
+// way to only prepare a statement, but it prepares the statements
+// with a binding before calling it; failing the binding stops the
+// query right after the preparation.
+func (s *Session) Prepare(q string) error {
+	stmt, err := s.statement(q)
+	if err != nil {
+		return err
+	}
+	err = s.session.Bind(stmt, func(*gocql.QueryInfo) ([]interface{}, error) {
+		return nil, errPrepared
+	}).Exec()
+	if err != nil && !errors.Is(err, errPrepared) {
+		return fmt.Errorf("failed to prepare %q: %v", stmt, err)
 	}
-	return s.session.Query(buf.String(), vals...), nil
+	return nil
 }
 
-func (s *Session) NewBatch(bt gocql.BatchType) *Batch {
+func (s *Session) NewBatch(bt gocql.BatchType) datastore.Batch {
 	return &Batch{
 		session: s,
-		batch:   gocql.NewBatch(bt),
+		batch:   s.session.NewBatch(bt),
 	}
 }
 
@@ -86,23 +290,24 @@ type Batch struct {
 }
 
 func (b *Batch) Query(q string, vals ...interface{}) error {
-	tmpl, err := template.New(q).Parse(q)
+	stmt, err := b.session.statement(q)
 	if err != nil {
 		return err
 	}
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, &queryData{
-		Keyspace: b.session.keyspace,
-		TTL:      b.session.ttl,
-	}); err != nil {
-		return err
-	}
-	b.batch.Query(buf.String(), vals...)
+	b.batch.Query(stmt, vals...)
 	return nil
 }
 
-func (s *Session) ExecuteBatch(b *Batch) error {
-	return s.session.ExecuteBatch(b.batch)
+func (b *Batch) Size() int {
+	return b.batch.Size()
+}
+
+func (s *Session) ExecuteBatch(b datastore.Batch) error {
+	batch, ok := b.(*Batch)
+	if !ok {
+		return fmt.Errorf("unexpected batch type %T", b)
+	}
+	return s.session.ExecuteBatch(batch.batch)
 }
 
 type queryData struct {
@@ -124,6 +329,7 @@ var initCQLs = []string{
 		event text,
 		unit text, 
 		value double,
+		int_value bigint,
 		created_at timestamp
 	);`,
 	`CREATE INDEX IF NOT EXISTS traceIndex ON {{.Keyspace}}.events ( trace_id );`,
@@ -131,3 +337,30 @@ var initCQLs = []string{
 	`CREATE INDEX IF NOT EXISTS eventIndex ON {{.Keyspace}}.events ( event );`,
 	`CREATE INDEX IF NOT EXISTS createdAtIndex ON {{.Keyspace}}.events ( created_at );`,
 }
+
+// Column is a column of the events table.
+type Column struct {
+	Name    string
+	CQLType string
+}
+
+// Columns are the columns of the events table myko
+// reads and writes.
+var Columns = []Column{
+	{Name: "id", CQLType: "uuid"},
+	{Name: "trace_id", CQLType: "text"},
+	{Name: "origin", CQLType: "text"},
+	{Name: "attr_key", CQLType: "text"},
+	{Name: "attr_value", CQLType: "text"},
+	{Name: "event", CQLType: "text"},
+	{Name: "unit", CQLType: "text"},
+	{Name: "value", CQLType: "double"},
+	{Name: "int_value", CQLType: "bigint"},
+	{Name: "created_at", CQLType: "timestamp"},
+}
+
+// addedColumns are the columns added to the events
+// table after its initial release.
+var addedColumns = []Column{
+	{Name: "int_value", CQLType: "bigint"},
+}
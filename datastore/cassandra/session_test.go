@@ -0,0 +1,83 @@
+package cassandra
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+// table returns the metadata of an events table with the columns
+// of cols, in that order.
+func table(cols ...Column) *gocql.TableMetadata {
+	t := &gocql.TableMetadata{Name: "events", Columns: make(map[string]*gocql.ColumnMetadata)}
+	for i, c := range cols {
+		t.OrderedColumns = append(t.OrderedColumns, c.Name)
+		t.Columns[c.Name] = &gocql.ColumnMetadata{Table: "events", Name: c.Name, ComponentIndex: i, Type: c.CQLType}
+	}
+	return t
+}
+
+// retyped returns the metadata of an events table with the
+// expected columns, changing the type of the named one.
+func retyped(name, typ string) *gocql.TableMetadata {
+	t := table(Columns...)
+	t.Columns[name].Type = typ
+	return t
+}
+
+func TestCheckColumn(t *testing.T) {
+	reversed := make([]Column, len(Columns))
+	for i, c := range Columns {
+		reversed[len(Columns)-1-i] = c
+	}
+	tests := []struct {
+		name        string
+		table       *gocql.TableMetadata
+		wantErr     bool
+		wantMissing bool
+	}{
+		{name: "expected", table: table(Columns...)},
+		{name: "reordered", table: table(reversed...)},
+		{
+			name: "extra columns",
+			table: table(append([]Column{
+				{Name: "tenant", CQLType: "text"},
+				{Name: "tags", CQLType: "set<text>"},
+			}, reversed...)...),
+		},
+		{name: "varchar", table: retyped("origin", "VARCHAR")},
+		{name: "missing column", table: table(Columns[1:]...), wantErr: true, wantMissing: true},
+		{name: "changed type", table: retyped("value", "float"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			for _, c := range Columns {
+				if err = checkColumn(tt.table, c); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkColumn() = %v, want error %v", err, tt.wantErr)
+			}
+			if missing := errors.Is(err, ErrMissingColumn); missing != tt.wantMissing {
+				t.Errorf("checkColumn() = %v, want missing column %v", err, tt.wantMissing)
+			}
+			if drift := IsSchemaDrift(err); drift != tt.wantErr {
+				t.Errorf("IsSchemaDrift(%v) = %v, want %v", err, drift, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsAdded(t *testing.T) {
+	for _, c := range addedColumns {
+		if !isAdded(c.Name) {
+			t.Errorf("isAdded(%q) = false, want true", c.Name)
+		}
+	}
+	if isAdded("id") {
+		t.Errorf("isAdded(%q) = true, want false", "id")
+	}
+}
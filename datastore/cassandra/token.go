@@ -0,0 +1,35 @@
+package cassandra
+
+import "math"
+
+// TokenRange is an inclusive range of Murmur3 partition tokens.
+type TokenRange struct {
+	Start int64
+	End   int64
+}
+
+// CQL returns the restriction of the range
+// and the values bound to its placeholders.
+func (r TokenRange) CQL() (string, []interface{}) {
+	return "token(id) >= ? AND token(id) <= ?", []interface{}{r.Start, r.End}
+}
+
+// SplitTokenRanges splits the entire token ring into n
+// contiguous ranges of roughly equal size.
+func SplitTokenRanges(n int) []TokenRange {
+	if n < 1 {
+		n = 1
+	}
+	width := math.MaxUint64 / uint64(n)
+	ranges := make([]TokenRange, n)
+	start := int64(math.MinInt64)
+	for i := range ranges {
+		end := int64(math.MaxInt64)
+		if i < n-1 {
+			end = start + int64(width-1)
+		}
+		ranges[i] = TokenRange{Start: start, End: end}
+		start = end + 1
+	}
+	return ranges
+}
@@ -0,0 +1,65 @@
+// Package datastore declares what the server needs from the
+// datastore the events are kept in. Statements are templates,
+// {{.Keyspace}} is replaced with the keyspace of the session.
+package datastore
+
+import (
+	"context"
+
+	"github.com/gocql/gocql"
+)
+
+// Session runs the statements of the server against a keyspace.
+type Session interface {
+	// Query returns a query running the statement
+	// with the given values bound to it.
+	Query(stmt string, vals ...interface{}) (Query, error)
+
+	// Prepare prepares the statement on the cluster without
+	// running it, so its first use doesn't pay the preparation.
+	Prepare(stmt string) error
+
+	NewBatch(typ gocql.BatchType) Batch
+	ExecuteBatch(b Batch) error
+
+	// InKeyspace returns a session sharing the connections
+	// of the session that runs the statements in the keyspace.
+	InKeyspace(keyspace string) Session
+
+	// Consistency returns the default consistency
+	// level of the queries.
+	Consistency() gocql.Consistency
+
+	// CheckSchema reports whether the events table
+	// has drifted from the expected one. It may also
+	// fail if the table can't be checked.
+	CheckSchema() error
+}
+
+type Query interface {
+	Consistency(c gocql.Consistency) Query
+	WithContext(ctx context.Context) Query
+	Exec() error
+
+	// Scan scans the first row, it returns gocql.ErrNotFound
+	// if there is none.
+	Scan(dest ...interface{}) error
+
+	// ScanCAS runs a conditional statement, it reports whether
+	// it is applied.
+	ScanCAS(dest ...interface{}) (applied bool, err error)
+
+	Iter() Iter
+}
+
+type Iter interface {
+	Scan(dest ...interface{}) bool
+	Close() error
+}
+
+type Batch interface {
+	Query(stmt string, vals ...interface{}) error
+
+	// Size returns the number of statements in the batch.
+	Size() int
+}
@@ -61,8 +61,9 @@ func main() {
 	}
 
 	resp, err := client.Query(ctx, &pb.QueryRequest{
-		TraceId: traceID,
-		Origin:  "create_user",
+		TraceId:        traceID,
+		Origin:         "create_user",
+		AllowFiltering: true,
 	})
 	if err != nil {
 		log.Fatalf("Failed to list events: %v", err)
@@ -1,4 +1,4 @@
-// Code generated by protoc-gen-twirp v8.1.2, DO NOT EDIT.
+// Code generated by protoc-gen-twirp v8.1.3, DO NOT EDIT.
 // source: proto/service.proto
 
 package mykopb
@@ -6,7 +6,7 @@ package mykopb
 import context "context"
 import fmt "fmt"
 import http "net/http"
-import ioutil "io/ioutil"
+import io "io"
 import json "encoding/json"
 import strconv "strconv"
 import strings "strings"
@@ -18,7 +18,6 @@ import ctxsetters "github.com/twitchtv/twirp/ctxsetters"
 
 import bytes "bytes"
 import errors "errors"
-import io "io"
 import path "path"
 import url "net/url"
 
@@ -35,9 +34,23 @@ const _ = twirp.TwirpPackageMinVersion_8_1_0
 type Service interface {
 	Query(context.Context, *QueryRequest) (*QueryResponse, error)
 
+	QueryTrace(context.Context, *QueryTraceRequest) (*QueryTraceResponse, error)
+
+	CompareOrigins(context.Context, *CompareOriginsRequest) (*CompareOriginsResponse, error)
+
 	InsertEvents(context.Context, *InsertEventsRequest) (*InsertEventsResponse, error)
 
 	DeleteEvents(context.Context, *DeleteEventsRequest) (*DeleteEventsResponse, error)
+
+	ReplaceEvents(context.Context, *ReplaceEventsRequest) (*ReplaceEventsResponse, error)
+
+	ListRecentEvents(context.Context, *ListRecentEventsRequest) (*ListRecentEventsResponse, error)
+
+	PauseIngestion(context.Context, *PauseIngestionRequest) (*PauseIngestionResponse, error)
+
+	ResumeIngestion(context.Context, *ResumeIngestionRequest) (*ResumeIngestionResponse, error)
+
+	SnapshotBuffer(context.Context, *SnapshotBufferRequest) (*SnapshotBufferResponse, error)
 }
 
 // =======================
@@ -46,7 +59,7 @@ type Service interface {
 
 type serviceProtobufClient struct {
 	client      HTTPClient
-	urls        [3]string
+	urls        [10]string
 	interceptor twirp.Interceptor
 	opts        twirp.ClientOptions
 }
@@ -63,7 +76,7 @@ func NewServiceProtobufClient(baseURL string, client HTTPClient, opts ...twirp.C
 		o(&clientOpts)
 	}
 
-	// Using ReadOpt allows backwards and forwads compatibility with new options in the future
+	// Using ReadOpt allows backwards and forwards compatibility with new options in the future
 	literalURLs := false
 	_ = clientOpts.ReadOpt("literalURLs", &literalURLs)
 	var pathPrefix string
@@ -74,10 +87,17 @@ func NewServiceProtobufClient(baseURL string, client HTTPClient, opts ...twirp.C
 	// Build method URLs: <baseURL>[<prefix>]/<package>.<Service>/<Method>
 	serviceURL := sanitizeBaseURL(baseURL)
 	serviceURL += baseServicePath(pathPrefix, "myko", "Service")
-	urls := [3]string{
+	urls := [10]string{
 		serviceURL + "Query",
+		serviceURL + "QueryTrace",
+		serviceURL + "CompareOrigins",
 		serviceURL + "InsertEvents",
 		serviceURL + "DeleteEvents",
+		serviceURL + "ReplaceEvents",
+		serviceURL + "ListRecentEvents",
+		serviceURL + "PauseIngestion",
+		serviceURL + "ResumeIngestion",
+		serviceURL + "SnapshotBuffer",
 	}
 
 	return &serviceProtobufClient{
@@ -134,6 +154,98 @@ func (c *serviceProtobufClient) callQuery(ctx context.Context, in *QueryRequest)
 	return out, nil
 }
 
+func (c *serviceProtobufClient) QueryTrace(ctx context.Context, in *QueryTraceRequest) (*QueryTraceResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "QueryTrace")
+	caller := c.callQueryTrace
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *QueryTraceRequest) (*QueryTraceResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*QueryTraceRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*QueryTraceRequest) when calling interceptor")
+					}
+					return c.callQueryTrace(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*QueryTraceResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*QueryTraceResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceProtobufClient) callQueryTrace(ctx context.Context, in *QueryTraceRequest) (*QueryTraceResponse, error) {
+	out := new(QueryTraceResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[1], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceProtobufClient) CompareOrigins(ctx context.Context, in *CompareOriginsRequest) (*CompareOriginsResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "CompareOrigins")
+	caller := c.callCompareOrigins
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *CompareOriginsRequest) (*CompareOriginsResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*CompareOriginsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*CompareOriginsRequest) when calling interceptor")
+					}
+					return c.callCompareOrigins(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*CompareOriginsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*CompareOriginsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceProtobufClient) callCompareOrigins(ctx context.Context, in *CompareOriginsRequest) (*CompareOriginsResponse, error) {
+	out := new(CompareOriginsResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[2], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
 func (c *serviceProtobufClient) InsertEvents(ctx context.Context, in *InsertEventsRequest) (*InsertEventsResponse, error) {
 	ctx = ctxsetters.WithPackageName(ctx, "myko")
 	ctx = ctxsetters.WithServiceName(ctx, "Service")
@@ -165,7 +277,7 @@ func (c *serviceProtobufClient) InsertEvents(ctx context.Context, in *InsertEven
 
 func (c *serviceProtobufClient) callInsertEvents(ctx context.Context, in *InsertEventsRequest) (*InsertEventsResponse, error) {
 	out := new(InsertEventsResponse)
-	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[1], in, out)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[3], in, out)
 	if err != nil {
 		twerr, ok := err.(twirp.Error)
 		if !ok {
@@ -211,7 +323,7 @@ func (c *serviceProtobufClient) DeleteEvents(ctx context.Context, in *DeleteEven
 
 func (c *serviceProtobufClient) callDeleteEvents(ctx context.Context, in *DeleteEventsRequest) (*DeleteEventsResponse, error) {
 	out := new(DeleteEventsResponse)
-	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[2], in, out)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[4], in, out)
 	if err != nil {
 		twerr, ok := err.(twirp.Error)
 		if !ok {
@@ -226,74 +338,118 @@ func (c *serviceProtobufClient) callDeleteEvents(ctx context.Context, in *Delete
 	return out, nil
 }
 
-// ===================
-// Service JSON Client
-// ===================
-
-type serviceJSONClient struct {
-	client      HTTPClient
-	urls        [3]string
-	interceptor twirp.Interceptor
-	opts        twirp.ClientOptions
+func (c *serviceProtobufClient) ReplaceEvents(ctx context.Context, in *ReplaceEventsRequest) (*ReplaceEventsResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "ReplaceEvents")
+	caller := c.callReplaceEvents
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *ReplaceEventsRequest) (*ReplaceEventsResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ReplaceEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ReplaceEventsRequest) when calling interceptor")
+					}
+					return c.callReplaceEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ReplaceEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ReplaceEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
 }
 
-// NewServiceJSONClient creates a JSON client that implements the Service interface.
-// It communicates using JSON and can be configured with a custom HTTPClient.
-func NewServiceJSONClient(baseURL string, client HTTPClient, opts ...twirp.ClientOption) Service {
-	if c, ok := client.(*http.Client); ok {
-		client = withoutRedirects(c)
+func (c *serviceProtobufClient) callReplaceEvents(ctx context.Context, in *ReplaceEventsRequest) (*ReplaceEventsResponse, error) {
+	out := new(ReplaceEventsResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[5], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
 	}
 
-	clientOpts := twirp.ClientOptions{}
-	for _, o := range opts {
-		o(&clientOpts)
-	}
+	callClientResponseReceived(ctx, c.opts.Hooks)
 
-	// Using ReadOpt allows backwards and forwads compatibility with new options in the future
-	literalURLs := false
-	_ = clientOpts.ReadOpt("literalURLs", &literalURLs)
-	var pathPrefix string
-	if ok := clientOpts.ReadOpt("pathPrefix", &pathPrefix); !ok {
-		pathPrefix = "/twirp" // default prefix
-	}
+	return out, nil
+}
 
-	// Build method URLs: <baseURL>[<prefix>]/<package>.<Service>/<Method>
-	serviceURL := sanitizeBaseURL(baseURL)
-	serviceURL += baseServicePath(pathPrefix, "myko", "Service")
-	urls := [3]string{
-		serviceURL + "Query",
-		serviceURL + "InsertEvents",
-		serviceURL + "DeleteEvents",
+func (c *serviceProtobufClient) ListRecentEvents(ctx context.Context, in *ListRecentEventsRequest) (*ListRecentEventsResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "ListRecentEvents")
+	caller := c.callListRecentEvents
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *ListRecentEventsRequest) (*ListRecentEventsResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ListRecentEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ListRecentEventsRequest) when calling interceptor")
+					}
+					return c.callListRecentEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ListRecentEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ListRecentEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
 	}
+	return caller(ctx, in)
+}
 
-	return &serviceJSONClient{
-		client:      client,
-		urls:        urls,
-		interceptor: twirp.ChainInterceptors(clientOpts.Interceptors...),
-		opts:        clientOpts,
+func (c *serviceProtobufClient) callListRecentEvents(ctx context.Context, in *ListRecentEventsRequest) (*ListRecentEventsResponse, error) {
+	out := new(ListRecentEventsResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[6], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
 	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
 }
 
-func (c *serviceJSONClient) Query(ctx context.Context, in *QueryRequest) (*QueryResponse, error) {
+func (c *serviceProtobufClient) PauseIngestion(ctx context.Context, in *PauseIngestionRequest) (*PauseIngestionResponse, error) {
 	ctx = ctxsetters.WithPackageName(ctx, "myko")
 	ctx = ctxsetters.WithServiceName(ctx, "Service")
-	ctx = ctxsetters.WithMethodName(ctx, "Query")
-	caller := c.callQuery
+	ctx = ctxsetters.WithMethodName(ctx, "PauseIngestion")
+	caller := c.callPauseIngestion
 	if c.interceptor != nil {
-		caller = func(ctx context.Context, req *QueryRequest) (*QueryResponse, error) {
+		caller = func(ctx context.Context, req *PauseIngestionRequest) (*PauseIngestionResponse, error) {
 			resp, err := c.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*QueryRequest)
+					typedReq, ok := req.(*PauseIngestionRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*QueryRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*PauseIngestionRequest) when calling interceptor")
 					}
-					return c.callQuery(ctx, typedReq)
+					return c.callPauseIngestion(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*QueryResponse)
+				typedResp, ok := resp.(*PauseIngestionResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*QueryResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*PauseIngestionResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -303,9 +459,9 @@ func (c *serviceJSONClient) Query(ctx context.Context, in *QueryRequest) (*Query
 	return caller(ctx, in)
 }
 
-func (c *serviceJSONClient) callQuery(ctx context.Context, in *QueryRequest) (*QueryResponse, error) {
-	out := new(QueryResponse)
-	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[0], in, out)
+func (c *serviceProtobufClient) callPauseIngestion(ctx context.Context, in *PauseIngestionRequest) (*PauseIngestionResponse, error) {
+	out := new(PauseIngestionResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[7], in, out)
 	if err != nil {
 		twerr, ok := err.(twirp.Error)
 		if !ok {
@@ -320,26 +476,26 @@ func (c *serviceJSONClient) callQuery(ctx context.Context, in *QueryRequest) (*Q
 	return out, nil
 }
 
-func (c *serviceJSONClient) InsertEvents(ctx context.Context, in *InsertEventsRequest) (*InsertEventsResponse, error) {
+func (c *serviceProtobufClient) ResumeIngestion(ctx context.Context, in *ResumeIngestionRequest) (*ResumeIngestionResponse, error) {
 	ctx = ctxsetters.WithPackageName(ctx, "myko")
 	ctx = ctxsetters.WithServiceName(ctx, "Service")
-	ctx = ctxsetters.WithMethodName(ctx, "InsertEvents")
-	caller := c.callInsertEvents
+	ctx = ctxsetters.WithMethodName(ctx, "ResumeIngestion")
+	caller := c.callResumeIngestion
 	if c.interceptor != nil {
-		caller = func(ctx context.Context, req *InsertEventsRequest) (*InsertEventsResponse, error) {
+		caller = func(ctx context.Context, req *ResumeIngestionRequest) (*ResumeIngestionResponse, error) {
 			resp, err := c.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*InsertEventsRequest)
+					typedReq, ok := req.(*ResumeIngestionRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*InsertEventsRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*ResumeIngestionRequest) when calling interceptor")
 					}
-					return c.callInsertEvents(ctx, typedReq)
+					return c.callResumeIngestion(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*InsertEventsResponse)
+				typedResp, ok := resp.(*ResumeIngestionResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*InsertEventsResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*ResumeIngestionResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -349,9 +505,9 @@ func (c *serviceJSONClient) InsertEvents(ctx context.Context, in *InsertEventsRe
 	return caller(ctx, in)
 }
 
-func (c *serviceJSONClient) callInsertEvents(ctx context.Context, in *InsertEventsRequest) (*InsertEventsResponse, error) {
-	out := new(InsertEventsResponse)
-	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[1], in, out)
+func (c *serviceProtobufClient) callResumeIngestion(ctx context.Context, in *ResumeIngestionRequest) (*ResumeIngestionResponse, error) {
+	out := new(ResumeIngestionResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[8], in, out)
 	if err != nil {
 		twerr, ok := err.(twirp.Error)
 		if !ok {
@@ -366,26 +522,26 @@ func (c *serviceJSONClient) callInsertEvents(ctx context.Context, in *InsertEven
 	return out, nil
 }
 
-func (c *serviceJSONClient) DeleteEvents(ctx context.Context, in *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+func (c *serviceProtobufClient) SnapshotBuffer(ctx context.Context, in *SnapshotBufferRequest) (*SnapshotBufferResponse, error) {
 	ctx = ctxsetters.WithPackageName(ctx, "myko")
 	ctx = ctxsetters.WithServiceName(ctx, "Service")
-	ctx = ctxsetters.WithMethodName(ctx, "DeleteEvents")
-	caller := c.callDeleteEvents
+	ctx = ctxsetters.WithMethodName(ctx, "SnapshotBuffer")
+	caller := c.callSnapshotBuffer
 	if c.interceptor != nil {
-		caller = func(ctx context.Context, req *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+		caller = func(ctx context.Context, req *SnapshotBufferRequest) (*SnapshotBufferResponse, error) {
 			resp, err := c.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*DeleteEventsRequest)
+					typedReq, ok := req.(*SnapshotBufferRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*DeleteEventsRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*SnapshotBufferRequest) when calling interceptor")
 					}
-					return c.callDeleteEvents(ctx, typedReq)
+					return c.callSnapshotBuffer(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*DeleteEventsResponse)
+				typedResp, ok := resp.(*SnapshotBufferResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*DeleteEventsResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*SnapshotBufferResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -395,137 +551,1933 @@ func (c *serviceJSONClient) DeleteEvents(ctx context.Context, in *DeleteEventsRe
 	return caller(ctx, in)
 }
 
-func (c *serviceJSONClient) callDeleteEvents(ctx context.Context, in *DeleteEventsRequest) (*DeleteEventsResponse, error) {
-	out := new(DeleteEventsResponse)
-	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[2], in, out)
+func (c *serviceProtobufClient) callSnapshotBuffer(ctx context.Context, in *SnapshotBufferRequest) (*SnapshotBufferResponse, error) {
+	out := new(SnapshotBufferResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[9], in, out)
 	if err != nil {
 		twerr, ok := err.(twirp.Error)
 		if !ok {
 			twerr = twirp.InternalErrorWith(err)
 		}
-		callClientError(ctx, c.opts.Hooks, twerr)
-		return nil, err
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+// ===================
+// Service JSON Client
+// ===================
+
+type serviceJSONClient struct {
+	client      HTTPClient
+	urls        [10]string
+	interceptor twirp.Interceptor
+	opts        twirp.ClientOptions
+}
+
+// NewServiceJSONClient creates a JSON client that implements the Service interface.
+// It communicates using JSON and can be configured with a custom HTTPClient.
+func NewServiceJSONClient(baseURL string, client HTTPClient, opts ...twirp.ClientOption) Service {
+	if c, ok := client.(*http.Client); ok {
+		client = withoutRedirects(c)
+	}
+
+	clientOpts := twirp.ClientOptions{}
+	for _, o := range opts {
+		o(&clientOpts)
+	}
+
+	// Using ReadOpt allows backwards and forwards compatibility with new options in the future
+	literalURLs := false
+	_ = clientOpts.ReadOpt("literalURLs", &literalURLs)
+	var pathPrefix string
+	if ok := clientOpts.ReadOpt("pathPrefix", &pathPrefix); !ok {
+		pathPrefix = "/twirp" // default prefix
+	}
+
+	// Build method URLs: <baseURL>[<prefix>]/<package>.<Service>/<Method>
+	serviceURL := sanitizeBaseURL(baseURL)
+	serviceURL += baseServicePath(pathPrefix, "myko", "Service")
+	urls := [10]string{
+		serviceURL + "Query",
+		serviceURL + "QueryTrace",
+		serviceURL + "CompareOrigins",
+		serviceURL + "InsertEvents",
+		serviceURL + "DeleteEvents",
+		serviceURL + "ReplaceEvents",
+		serviceURL + "ListRecentEvents",
+		serviceURL + "PauseIngestion",
+		serviceURL + "ResumeIngestion",
+		serviceURL + "SnapshotBuffer",
+	}
+
+	return &serviceJSONClient{
+		client:      client,
+		urls:        urls,
+		interceptor: twirp.ChainInterceptors(clientOpts.Interceptors...),
+		opts:        clientOpts,
+	}
+}
+
+func (c *serviceJSONClient) Query(ctx context.Context, in *QueryRequest) (*QueryResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "Query")
+	caller := c.callQuery
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *QueryRequest) (*QueryResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*QueryRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*QueryRequest) when calling interceptor")
+					}
+					return c.callQuery(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*QueryResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*QueryResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callQuery(ctx context.Context, in *QueryRequest) (*QueryResponse, error) {
+	out := new(QueryResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[0], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) QueryTrace(ctx context.Context, in *QueryTraceRequest) (*QueryTraceResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "QueryTrace")
+	caller := c.callQueryTrace
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *QueryTraceRequest) (*QueryTraceResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*QueryTraceRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*QueryTraceRequest) when calling interceptor")
+					}
+					return c.callQueryTrace(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*QueryTraceResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*QueryTraceResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callQueryTrace(ctx context.Context, in *QueryTraceRequest) (*QueryTraceResponse, error) {
+	out := new(QueryTraceResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[1], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) CompareOrigins(ctx context.Context, in *CompareOriginsRequest) (*CompareOriginsResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "CompareOrigins")
+	caller := c.callCompareOrigins
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *CompareOriginsRequest) (*CompareOriginsResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*CompareOriginsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*CompareOriginsRequest) when calling interceptor")
+					}
+					return c.callCompareOrigins(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*CompareOriginsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*CompareOriginsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callCompareOrigins(ctx context.Context, in *CompareOriginsRequest) (*CompareOriginsResponse, error) {
+	out := new(CompareOriginsResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[2], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) InsertEvents(ctx context.Context, in *InsertEventsRequest) (*InsertEventsResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "InsertEvents")
+	caller := c.callInsertEvents
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *InsertEventsRequest) (*InsertEventsResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*InsertEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*InsertEventsRequest) when calling interceptor")
+					}
+					return c.callInsertEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*InsertEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*InsertEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callInsertEvents(ctx context.Context, in *InsertEventsRequest) (*InsertEventsResponse, error) {
+	out := new(InsertEventsResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[3], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) DeleteEvents(ctx context.Context, in *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "DeleteEvents")
+	caller := c.callDeleteEvents
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*DeleteEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*DeleteEventsRequest) when calling interceptor")
+					}
+					return c.callDeleteEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*DeleteEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*DeleteEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callDeleteEvents(ctx context.Context, in *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+	out := new(DeleteEventsResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[4], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) ReplaceEvents(ctx context.Context, in *ReplaceEventsRequest) (*ReplaceEventsResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "ReplaceEvents")
+	caller := c.callReplaceEvents
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *ReplaceEventsRequest) (*ReplaceEventsResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ReplaceEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ReplaceEventsRequest) when calling interceptor")
+					}
+					return c.callReplaceEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ReplaceEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ReplaceEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callReplaceEvents(ctx context.Context, in *ReplaceEventsRequest) (*ReplaceEventsResponse, error) {
+	out := new(ReplaceEventsResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[5], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) ListRecentEvents(ctx context.Context, in *ListRecentEventsRequest) (*ListRecentEventsResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "ListRecentEvents")
+	caller := c.callListRecentEvents
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *ListRecentEventsRequest) (*ListRecentEventsResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ListRecentEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ListRecentEventsRequest) when calling interceptor")
+					}
+					return c.callListRecentEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ListRecentEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ListRecentEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callListRecentEvents(ctx context.Context, in *ListRecentEventsRequest) (*ListRecentEventsResponse, error) {
+	out := new(ListRecentEventsResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[6], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) PauseIngestion(ctx context.Context, in *PauseIngestionRequest) (*PauseIngestionResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "PauseIngestion")
+	caller := c.callPauseIngestion
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *PauseIngestionRequest) (*PauseIngestionResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PauseIngestionRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PauseIngestionRequest) when calling interceptor")
+					}
+					return c.callPauseIngestion(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PauseIngestionResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PauseIngestionResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callPauseIngestion(ctx context.Context, in *PauseIngestionRequest) (*PauseIngestionResponse, error) {
+	out := new(PauseIngestionResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[7], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) ResumeIngestion(ctx context.Context, in *ResumeIngestionRequest) (*ResumeIngestionResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "ResumeIngestion")
+	caller := c.callResumeIngestion
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *ResumeIngestionRequest) (*ResumeIngestionResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ResumeIngestionRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ResumeIngestionRequest) when calling interceptor")
+					}
+					return c.callResumeIngestion(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ResumeIngestionResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ResumeIngestionResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callResumeIngestion(ctx context.Context, in *ResumeIngestionRequest) (*ResumeIngestionResponse, error) {
+	out := new(ResumeIngestionResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[8], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *serviceJSONClient) SnapshotBuffer(ctx context.Context, in *SnapshotBufferRequest) (*SnapshotBufferResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithMethodName(ctx, "SnapshotBuffer")
+	caller := c.callSnapshotBuffer
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *SnapshotBufferRequest) (*SnapshotBufferResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*SnapshotBufferRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*SnapshotBufferRequest) when calling interceptor")
+					}
+					return c.callSnapshotBuffer(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*SnapshotBufferResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*SnapshotBufferResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *serviceJSONClient) callSnapshotBuffer(ctx context.Context, in *SnapshotBufferRequest) (*SnapshotBufferResponse, error) {
+	out := new(SnapshotBufferResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[9], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+// ======================
+// Service Server Handler
+// ======================
+
+type serviceServer struct {
+	Service
+	interceptor      twirp.Interceptor
+	hooks            *twirp.ServerHooks
+	pathPrefix       string // prefix for routing
+	jsonSkipDefaults bool   // do not include unpopulated fields (default values) in the response
+	jsonCamelCase    bool   // JSON fields are serialized as lowerCamelCase rather than keeping the original proto names
+}
+
+// NewServiceServer builds a TwirpServer that can be used as an http.Handler to handle
+// HTTP requests that are routed to the right method in the provided svc implementation.
+// The opts are twirp.ServerOption modifiers, for example twirp.WithServerHooks(hooks).
+func NewServiceServer(svc Service, opts ...interface{}) TwirpServer {
+	serverOpts := newServerOpts(opts)
+
+	// Using ReadOpt allows backwards and forwards compatibility with new options in the future
+	jsonSkipDefaults := false
+	_ = serverOpts.ReadOpt("jsonSkipDefaults", &jsonSkipDefaults)
+	jsonCamelCase := false
+	_ = serverOpts.ReadOpt("jsonCamelCase", &jsonCamelCase)
+	var pathPrefix string
+	if ok := serverOpts.ReadOpt("pathPrefix", &pathPrefix); !ok {
+		pathPrefix = "/twirp" // default prefix
+	}
+
+	return &serviceServer{
+		Service:          svc,
+		hooks:            serverOpts.Hooks,
+		interceptor:      twirp.ChainInterceptors(serverOpts.Interceptors...),
+		pathPrefix:       pathPrefix,
+		jsonSkipDefaults: jsonSkipDefaults,
+		jsonCamelCase:    jsonCamelCase,
+	}
+}
+
+// writeError writes an HTTP response with a valid Twirp error format, and triggers hooks.
+// If err is not a twirp.Error, it will get wrapped with twirp.InternalErrorWith(err)
+func (s *serviceServer) writeError(ctx context.Context, resp http.ResponseWriter, err error) {
+	writeError(ctx, resp, err, s.hooks)
+}
+
+// handleRequestBodyError is used to handle error when the twirp server cannot read request
+func (s *serviceServer) handleRequestBodyError(ctx context.Context, resp http.ResponseWriter, msg string, err error) {
+	if context.Canceled == ctx.Err() {
+		s.writeError(ctx, resp, twirp.NewError(twirp.Canceled, "failed to read request: context canceled"))
+		return
+	}
+	if context.DeadlineExceeded == ctx.Err() {
+		s.writeError(ctx, resp, twirp.NewError(twirp.DeadlineExceeded, "failed to read request: deadline exceeded"))
+		return
+	}
+	s.writeError(ctx, resp, twirp.WrapError(malformedRequestError(msg), err))
+}
+
+// ServicePathPrefix is a convenience constant that may identify URL paths.
+// Should be used with caution, it only matches routes generated by Twirp Go clients,
+// with the default "/twirp" prefix and default CamelCase service and method names.
+// More info: https://twitchtv.github.io/twirp/docs/routing.html
+const ServicePathPrefix = "/twirp/myko.Service/"
+
+func (s *serviceServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	ctx = ctxsetters.WithPackageName(ctx, "myko")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+	ctx = ctxsetters.WithResponseWriter(ctx, resp)
+
+	var err error
+	ctx, err = callRequestReceived(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	if req.Method != "POST" {
+		msg := fmt.Sprintf("unsupported method %q (only POST is allowed)", req.Method)
+		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
+		return
+	}
+
+	// Verify path format: [<prefix>]/<package>.<Service>/<Method>
+	prefix, pkgService, method := parseTwirpPath(req.URL.Path)
+	if pkgService != "myko.Service" {
+		msg := fmt.Sprintf("no handler for path %q", req.URL.Path)
+		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
+		return
+	}
+	if prefix != s.pathPrefix {
+		msg := fmt.Sprintf("invalid path prefix %q, expected %q, on path %q", prefix, s.pathPrefix, req.URL.Path)
+		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
+		return
+	}
+
+	switch method {
+	case "Query":
+		s.serveQuery(ctx, resp, req)
+		return
+	case "QueryTrace":
+		s.serveQueryTrace(ctx, resp, req)
+		return
+	case "CompareOrigins":
+		s.serveCompareOrigins(ctx, resp, req)
+		return
+	case "InsertEvents":
+		s.serveInsertEvents(ctx, resp, req)
+		return
+	case "DeleteEvents":
+		s.serveDeleteEvents(ctx, resp, req)
+		return
+	case "ReplaceEvents":
+		s.serveReplaceEvents(ctx, resp, req)
+		return
+	case "ListRecentEvents":
+		s.serveListRecentEvents(ctx, resp, req)
+		return
+	case "PauseIngestion":
+		s.servePauseIngestion(ctx, resp, req)
+		return
+	case "ResumeIngestion":
+		s.serveResumeIngestion(ctx, resp, req)
+		return
+	case "SnapshotBuffer":
+		s.serveSnapshotBuffer(ctx, resp, req)
+		return
+	default:
+		msg := fmt.Sprintf("no handler for path %q", req.URL.Path)
+		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
+		return
+	}
+}
+
+func (s *serviceServer) serveQuery(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveQueryJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveQueryProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceServer) serveQueryJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "Query")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(QueryRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.Service.Query
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *QueryRequest) (*QueryResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*QueryRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*QueryRequest) when calling interceptor")
+					}
+					return s.Service.Query(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*QueryResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*QueryResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *QueryResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *QueryResponse and nil error while calling Query. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveQueryProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "Query")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(QueryRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.Service.Query
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *QueryRequest) (*QueryResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*QueryRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*QueryRequest) when calling interceptor")
+					}
+					return s.Service.Query(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*QueryResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*QueryResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *QueryResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *QueryResponse and nil error while calling Query. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveQueryTrace(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveQueryTraceJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveQueryTraceProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceServer) serveQueryTraceJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "QueryTrace")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(QueryTraceRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.Service.QueryTrace
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *QueryTraceRequest) (*QueryTraceResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*QueryTraceRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*QueryTraceRequest) when calling interceptor")
+					}
+					return s.Service.QueryTrace(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*QueryTraceResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*QueryTraceResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *QueryTraceResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *QueryTraceResponse and nil error while calling QueryTrace. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveQueryTraceProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "QueryTrace")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(QueryTraceRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.Service.QueryTrace
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *QueryTraceRequest) (*QueryTraceResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*QueryTraceRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*QueryTraceRequest) when calling interceptor")
+					}
+					return s.Service.QueryTrace(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*QueryTraceResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*QueryTraceResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *QueryTraceResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *QueryTraceResponse and nil error while calling QueryTrace. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveCompareOrigins(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveCompareOriginsJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveCompareOriginsProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceServer) serveCompareOriginsJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "CompareOrigins")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(CompareOriginsRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.Service.CompareOrigins
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *CompareOriginsRequest) (*CompareOriginsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*CompareOriginsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*CompareOriginsRequest) when calling interceptor")
+					}
+					return s.Service.CompareOrigins(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*CompareOriginsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*CompareOriginsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *CompareOriginsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *CompareOriginsResponse and nil error while calling CompareOrigins. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveCompareOriginsProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "CompareOrigins")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(CompareOriginsRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.Service.CompareOrigins
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *CompareOriginsRequest) (*CompareOriginsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*CompareOriginsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*CompareOriginsRequest) when calling interceptor")
+					}
+					return s.Service.CompareOrigins(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*CompareOriginsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*CompareOriginsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *CompareOriginsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *CompareOriginsResponse and nil error while calling CompareOrigins. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveInsertEvents(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveInsertEventsJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveInsertEventsProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceServer) serveInsertEventsJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "InsertEvents")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(InsertEventsRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.Service.InsertEvents
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *InsertEventsRequest) (*InsertEventsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*InsertEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*InsertEventsRequest) when calling interceptor")
+					}
+					return s.Service.InsertEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*InsertEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*InsertEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *InsertEventsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *InsertEventsResponse and nil error while calling InsertEvents. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveInsertEventsProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "InsertEvents")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(InsertEventsRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.Service.InsertEvents
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *InsertEventsRequest) (*InsertEventsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*InsertEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*InsertEventsRequest) when calling interceptor")
+					}
+					return s.Service.InsertEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*InsertEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*InsertEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *InsertEventsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *InsertEventsResponse and nil error while calling InsertEvents. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveDeleteEvents(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveDeleteEventsJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveDeleteEventsProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceServer) serveDeleteEventsJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "DeleteEvents")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(DeleteEventsRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.Service.DeleteEvents
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*DeleteEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*DeleteEventsRequest) when calling interceptor")
+					}
+					return s.Service.DeleteEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*DeleteEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*DeleteEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *DeleteEventsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *DeleteEventsResponse and nil error while calling DeleteEvents. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveDeleteEventsProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "DeleteEvents")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(DeleteEventsRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.Service.DeleteEvents
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*DeleteEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*DeleteEventsRequest) when calling interceptor")
+					}
+					return s.Service.DeleteEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*DeleteEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*DeleteEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *DeleteEventsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *DeleteEventsResponse and nil error while calling DeleteEvents. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveReplaceEvents(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveReplaceEventsJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveReplaceEventsProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceServer) serveReplaceEventsJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "ReplaceEvents")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(ReplaceEventsRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.Service.ReplaceEvents
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *ReplaceEventsRequest) (*ReplaceEventsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ReplaceEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ReplaceEventsRequest) when calling interceptor")
+					}
+					return s.Service.ReplaceEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ReplaceEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ReplaceEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *ReplaceEventsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *ReplaceEventsResponse and nil error while calling ReplaceEvents. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveReplaceEventsProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "ReplaceEvents")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(ReplaceEventsRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.Service.ReplaceEvents
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *ReplaceEventsRequest) (*ReplaceEventsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ReplaceEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ReplaceEventsRequest) when calling interceptor")
+					}
+					return s.Service.ReplaceEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ReplaceEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ReplaceEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *ReplaceEventsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *ReplaceEventsResponse and nil error while calling ReplaceEvents. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceServer) serveListRecentEvents(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveListRecentEventsJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveListRecentEventsProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceServer) serveListRecentEventsJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "ListRecentEvents")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(ListRecentEventsRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.Service.ListRecentEvents
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *ListRecentEventsRequest) (*ListRecentEventsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ListRecentEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ListRecentEventsRequest) when calling interceptor")
+					}
+					return s.Service.ListRecentEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ListRecentEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ListRecentEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
 	}
 
-	callClientResponseReceived(ctx, c.opts.Hooks)
-
-	return out, nil
-}
-
-// ======================
-// Service Server Handler
-// ======================
+	// Call service method
+	var respContent *ListRecentEventsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
 
-type serviceServer struct {
-	Service
-	interceptor      twirp.Interceptor
-	hooks            *twirp.ServerHooks
-	pathPrefix       string // prefix for routing
-	jsonSkipDefaults bool   // do not include unpopulated fields (default values) in the response
-	jsonCamelCase    bool   // JSON fields are serialized as lowerCamelCase rather than keeping the original proto names
-}
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *ListRecentEventsResponse and nil error while calling ListRecentEvents. nil responses are not supported"))
+		return
+	}
 
-// NewServiceServer builds a TwirpServer that can be used as an http.Handler to handle
-// HTTP requests that are routed to the right method in the provided svc implementation.
-// The opts are twirp.ServerOption modifiers, for example twirp.WithServerHooks(hooks).
-func NewServiceServer(svc Service, opts ...interface{}) TwirpServer {
-	serverOpts := newServerOpts(opts)
+	ctx = callResponsePrepared(ctx, s.hooks)
 
-	// Using ReadOpt allows backwards and forwads compatibility with new options in the future
-	jsonSkipDefaults := false
-	_ = serverOpts.ReadOpt("jsonSkipDefaults", &jsonSkipDefaults)
-	jsonCamelCase := false
-	_ = serverOpts.ReadOpt("jsonCamelCase", &jsonCamelCase)
-	var pathPrefix string
-	if ok := serverOpts.ReadOpt("pathPrefix", &pathPrefix); !ok {
-		pathPrefix = "/twirp" // default prefix
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
 	}
 
-	return &serviceServer{
-		Service:          svc,
-		hooks:            serverOpts.Hooks,
-		interceptor:      twirp.ChainInterceptors(serverOpts.Interceptors...),
-		pathPrefix:       pathPrefix,
-		jsonSkipDefaults: jsonSkipDefaults,
-		jsonCamelCase:    jsonCamelCase,
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
 	}
+	callResponseSent(ctx, s.hooks)
 }
 
-// writeError writes an HTTP response with a valid Twirp error format, and triggers hooks.
-// If err is not a twirp.Error, it will get wrapped with twirp.InternalErrorWith(err)
-func (s *serviceServer) writeError(ctx context.Context, resp http.ResponseWriter, err error) {
-	writeError(ctx, resp, err, s.hooks)
-}
+func (s *serviceServer) serveListRecentEventsProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "ListRecentEvents")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
 
-// handleRequestBodyError is used to handle error when the twirp server cannot read request
-func (s *serviceServer) handleRequestBodyError(ctx context.Context, resp http.ResponseWriter, msg string, err error) {
-	if context.Canceled == ctx.Err() {
-		s.writeError(ctx, resp, twirp.NewError(twirp.Canceled, "failed to read request: context canceled"))
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
 		return
 	}
-	if context.DeadlineExceeded == ctx.Err() {
-		s.writeError(ctx, resp, twirp.NewError(twirp.DeadlineExceeded, "failed to read request: deadline exceeded"))
+	reqContent := new(ListRecentEventsRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
 		return
 	}
-	s.writeError(ctx, resp, twirp.WrapError(malformedRequestError(msg), err))
-}
 
-// ServicePathPrefix is a convenience constant that may identify URL paths.
-// Should be used with caution, it only matches routes generated by Twirp Go clients,
-// with the default "/twirp" prefix and default CamelCase service and method names.
-// More info: https://twitchtv.github.io/twirp/docs/routing.html
-const ServicePathPrefix = "/twirp/myko.Service/"
+	handler := s.Service.ListRecentEvents
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *ListRecentEventsRequest) (*ListRecentEventsResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*ListRecentEventsRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*ListRecentEventsRequest) when calling interceptor")
+					}
+					return s.Service.ListRecentEvents(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*ListRecentEventsResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*ListRecentEventsResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
 
-func (s *serviceServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	ctx = ctxsetters.WithPackageName(ctx, "myko")
-	ctx = ctxsetters.WithServiceName(ctx, "Service")
-	ctx = ctxsetters.WithResponseWriter(ctx, resp)
+	// Call service method
+	var respContent *ListRecentEventsResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
 
-	var err error
-	ctx, err = callRequestReceived(ctx, s.hooks)
 	if err != nil {
 		s.writeError(ctx, resp, err)
 		return
 	}
-
-	if req.Method != "POST" {
-		msg := fmt.Sprintf("unsupported method %q (only POST is allowed)", req.Method)
-		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *ListRecentEventsResponse and nil error while calling ListRecentEvents. nil responses are not supported"))
 		return
 	}
 
-	// Verify path format: [<prefix>]/<package>.<Service>/<Method>
-	prefix, pkgService, method := parseTwirpPath(req.URL.Path)
-	if pkgService != "myko.Service" {
-		msg := fmt.Sprintf("no handler for path %q", req.URL.Path)
-		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
-		return
-	}
-	if prefix != s.pathPrefix {
-		msg := fmt.Sprintf("invalid path prefix %q, expected %q, on path %q", prefix, s.pathPrefix, req.URL.Path)
-		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
 		return
 	}
 
-	switch method {
-	case "Query":
-		s.serveQuery(ctx, resp, req)
-		return
-	case "InsertEvents":
-		s.serveInsertEvents(ctx, resp, req)
-		return
-	case "DeleteEvents":
-		s.serveDeleteEvents(ctx, resp, req)
-		return
-	default:
-		msg := fmt.Sprintf("no handler for path %q", req.URL.Path)
-		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
-		return
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
 	}
+	callResponseSent(ctx, s.hooks)
 }
 
-func (s *serviceServer) serveQuery(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) servePauseIngestion(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	header := req.Header.Get("Content-Type")
 	i := strings.Index(header, ";")
 	if i == -1 {
@@ -533,9 +2485,9 @@ func (s *serviceServer) serveQuery(ctx context.Context, resp http.ResponseWriter
 	}
 	switch strings.TrimSpace(strings.ToLower(header[:i])) {
 	case "application/json":
-		s.serveQueryJSON(ctx, resp, req)
+		s.servePauseIngestionJSON(ctx, resp, req)
 	case "application/protobuf":
-		s.serveQueryProtobuf(ctx, resp, req)
+		s.servePauseIngestionProtobuf(ctx, resp, req)
 	default:
 		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
 		twerr := badRouteError(msg, req.Method, req.URL.Path)
@@ -543,9 +2495,9 @@ func (s *serviceServer) serveQuery(ctx context.Context, resp http.ResponseWriter
 	}
 }
 
-func (s *serviceServer) serveQueryJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) servePauseIngestionJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	var err error
-	ctx = ctxsetters.WithMethodName(ctx, "Query")
+	ctx = ctxsetters.WithMethodName(ctx, "PauseIngestion")
 	ctx, err = callRequestRouted(ctx, s.hooks)
 	if err != nil {
 		s.writeError(ctx, resp, err)
@@ -558,29 +2510,29 @@ func (s *serviceServer) serveQueryJSON(ctx context.Context, resp http.ResponseWr
 		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
 		return
 	}
-	reqContent := new(QueryRequest)
+	reqContent := new(PauseIngestionRequest)
 	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
 	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
 		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
 		return
 	}
 
-	handler := s.Service.Query
+	handler := s.Service.PauseIngestion
 	if s.interceptor != nil {
-		handler = func(ctx context.Context, req *QueryRequest) (*QueryResponse, error) {
+		handler = func(ctx context.Context, req *PauseIngestionRequest) (*PauseIngestionResponse, error) {
 			resp, err := s.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*QueryRequest)
+					typedReq, ok := req.(*PauseIngestionRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*QueryRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*PauseIngestionRequest) when calling interceptor")
 					}
-					return s.Service.Query(ctx, typedReq)
+					return s.Service.PauseIngestion(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*QueryResponse)
+				typedResp, ok := resp.(*PauseIngestionResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*QueryResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*PauseIngestionResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -589,7 +2541,7 @@ func (s *serviceServer) serveQueryJSON(ctx context.Context, resp http.ResponseWr
 	}
 
 	// Call service method
-	var respContent *QueryResponse
+	var respContent *PauseIngestionResponse
 	func() {
 		defer ensurePanicResponses(ctx, resp, s.hooks)
 		respContent, err = handler(ctx, reqContent)
@@ -600,7 +2552,7 @@ func (s *serviceServer) serveQueryJSON(ctx context.Context, resp http.ResponseWr
 		return
 	}
 	if respContent == nil {
-		s.writeError(ctx, resp, twirp.InternalError("received a nil *QueryResponse and nil error while calling Query. nil responses are not supported"))
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *PauseIngestionResponse and nil error while calling PauseIngestion. nil responses are not supported"))
 		return
 	}
 
@@ -626,42 +2578,42 @@ func (s *serviceServer) serveQueryJSON(ctx context.Context, resp http.ResponseWr
 	callResponseSent(ctx, s.hooks)
 }
 
-func (s *serviceServer) serveQueryProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) servePauseIngestionProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	var err error
-	ctx = ctxsetters.WithMethodName(ctx, "Query")
+	ctx = ctxsetters.WithMethodName(ctx, "PauseIngestion")
 	ctx, err = callRequestRouted(ctx, s.hooks)
 	if err != nil {
 		s.writeError(ctx, resp, err)
 		return
 	}
 
-	buf, err := ioutil.ReadAll(req.Body)
+	buf, err := io.ReadAll(req.Body)
 	if err != nil {
 		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
 		return
 	}
-	reqContent := new(QueryRequest)
+	reqContent := new(PauseIngestionRequest)
 	if err = proto.Unmarshal(buf, reqContent); err != nil {
 		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
 		return
 	}
 
-	handler := s.Service.Query
+	handler := s.Service.PauseIngestion
 	if s.interceptor != nil {
-		handler = func(ctx context.Context, req *QueryRequest) (*QueryResponse, error) {
+		handler = func(ctx context.Context, req *PauseIngestionRequest) (*PauseIngestionResponse, error) {
 			resp, err := s.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*QueryRequest)
+					typedReq, ok := req.(*PauseIngestionRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*QueryRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*PauseIngestionRequest) when calling interceptor")
 					}
-					return s.Service.Query(ctx, typedReq)
+					return s.Service.PauseIngestion(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*QueryResponse)
+				typedResp, ok := resp.(*PauseIngestionResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*QueryResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*PauseIngestionResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -670,7 +2622,7 @@ func (s *serviceServer) serveQueryProtobuf(ctx context.Context, resp http.Respon
 	}
 
 	// Call service method
-	var respContent *QueryResponse
+	var respContent *PauseIngestionResponse
 	func() {
 		defer ensurePanicResponses(ctx, resp, s.hooks)
 		respContent, err = handler(ctx, reqContent)
@@ -681,7 +2633,7 @@ func (s *serviceServer) serveQueryProtobuf(ctx context.Context, resp http.Respon
 		return
 	}
 	if respContent == nil {
-		s.writeError(ctx, resp, twirp.InternalError("received a nil *QueryResponse and nil error while calling Query. nil responses are not supported"))
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *PauseIngestionResponse and nil error while calling PauseIngestion. nil responses are not supported"))
 		return
 	}
 
@@ -705,7 +2657,7 @@ func (s *serviceServer) serveQueryProtobuf(ctx context.Context, resp http.Respon
 	callResponseSent(ctx, s.hooks)
 }
 
-func (s *serviceServer) serveInsertEvents(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) serveResumeIngestion(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	header := req.Header.Get("Content-Type")
 	i := strings.Index(header, ";")
 	if i == -1 {
@@ -713,9 +2665,9 @@ func (s *serviceServer) serveInsertEvents(ctx context.Context, resp http.Respons
 	}
 	switch strings.TrimSpace(strings.ToLower(header[:i])) {
 	case "application/json":
-		s.serveInsertEventsJSON(ctx, resp, req)
+		s.serveResumeIngestionJSON(ctx, resp, req)
 	case "application/protobuf":
-		s.serveInsertEventsProtobuf(ctx, resp, req)
+		s.serveResumeIngestionProtobuf(ctx, resp, req)
 	default:
 		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
 		twerr := badRouteError(msg, req.Method, req.URL.Path)
@@ -723,9 +2675,9 @@ func (s *serviceServer) serveInsertEvents(ctx context.Context, resp http.Respons
 	}
 }
 
-func (s *serviceServer) serveInsertEventsJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) serveResumeIngestionJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	var err error
-	ctx = ctxsetters.WithMethodName(ctx, "InsertEvents")
+	ctx = ctxsetters.WithMethodName(ctx, "ResumeIngestion")
 	ctx, err = callRequestRouted(ctx, s.hooks)
 	if err != nil {
 		s.writeError(ctx, resp, err)
@@ -738,29 +2690,29 @@ func (s *serviceServer) serveInsertEventsJSON(ctx context.Context, resp http.Res
 		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
 		return
 	}
-	reqContent := new(InsertEventsRequest)
+	reqContent := new(ResumeIngestionRequest)
 	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
 	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
 		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
 		return
 	}
 
-	handler := s.Service.InsertEvents
+	handler := s.Service.ResumeIngestion
 	if s.interceptor != nil {
-		handler = func(ctx context.Context, req *InsertEventsRequest) (*InsertEventsResponse, error) {
+		handler = func(ctx context.Context, req *ResumeIngestionRequest) (*ResumeIngestionResponse, error) {
 			resp, err := s.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*InsertEventsRequest)
+					typedReq, ok := req.(*ResumeIngestionRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*InsertEventsRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*ResumeIngestionRequest) when calling interceptor")
 					}
-					return s.Service.InsertEvents(ctx, typedReq)
+					return s.Service.ResumeIngestion(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*InsertEventsResponse)
+				typedResp, ok := resp.(*ResumeIngestionResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*InsertEventsResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*ResumeIngestionResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -769,7 +2721,7 @@ func (s *serviceServer) serveInsertEventsJSON(ctx context.Context, resp http.Res
 	}
 
 	// Call service method
-	var respContent *InsertEventsResponse
+	var respContent *ResumeIngestionResponse
 	func() {
 		defer ensurePanicResponses(ctx, resp, s.hooks)
 		respContent, err = handler(ctx, reqContent)
@@ -780,7 +2732,7 @@ func (s *serviceServer) serveInsertEventsJSON(ctx context.Context, resp http.Res
 		return
 	}
 	if respContent == nil {
-		s.writeError(ctx, resp, twirp.InternalError("received a nil *InsertEventsResponse and nil error while calling InsertEvents. nil responses are not supported"))
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *ResumeIngestionResponse and nil error while calling ResumeIngestion. nil responses are not supported"))
 		return
 	}
 
@@ -806,42 +2758,42 @@ func (s *serviceServer) serveInsertEventsJSON(ctx context.Context, resp http.Res
 	callResponseSent(ctx, s.hooks)
 }
 
-func (s *serviceServer) serveInsertEventsProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) serveResumeIngestionProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	var err error
-	ctx = ctxsetters.WithMethodName(ctx, "InsertEvents")
+	ctx = ctxsetters.WithMethodName(ctx, "ResumeIngestion")
 	ctx, err = callRequestRouted(ctx, s.hooks)
 	if err != nil {
 		s.writeError(ctx, resp, err)
 		return
 	}
 
-	buf, err := ioutil.ReadAll(req.Body)
+	buf, err := io.ReadAll(req.Body)
 	if err != nil {
 		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
 		return
 	}
-	reqContent := new(InsertEventsRequest)
+	reqContent := new(ResumeIngestionRequest)
 	if err = proto.Unmarshal(buf, reqContent); err != nil {
 		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
 		return
 	}
 
-	handler := s.Service.InsertEvents
+	handler := s.Service.ResumeIngestion
 	if s.interceptor != nil {
-		handler = func(ctx context.Context, req *InsertEventsRequest) (*InsertEventsResponse, error) {
+		handler = func(ctx context.Context, req *ResumeIngestionRequest) (*ResumeIngestionResponse, error) {
 			resp, err := s.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*InsertEventsRequest)
+					typedReq, ok := req.(*ResumeIngestionRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*InsertEventsRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*ResumeIngestionRequest) when calling interceptor")
 					}
-					return s.Service.InsertEvents(ctx, typedReq)
+					return s.Service.ResumeIngestion(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*InsertEventsResponse)
+				typedResp, ok := resp.(*ResumeIngestionResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*InsertEventsResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*ResumeIngestionResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -850,7 +2802,7 @@ func (s *serviceServer) serveInsertEventsProtobuf(ctx context.Context, resp http
 	}
 
 	// Call service method
-	var respContent *InsertEventsResponse
+	var respContent *ResumeIngestionResponse
 	func() {
 		defer ensurePanicResponses(ctx, resp, s.hooks)
 		respContent, err = handler(ctx, reqContent)
@@ -861,7 +2813,7 @@ func (s *serviceServer) serveInsertEventsProtobuf(ctx context.Context, resp http
 		return
 	}
 	if respContent == nil {
-		s.writeError(ctx, resp, twirp.InternalError("received a nil *InsertEventsResponse and nil error while calling InsertEvents. nil responses are not supported"))
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *ResumeIngestionResponse and nil error while calling ResumeIngestion. nil responses are not supported"))
 		return
 	}
 
@@ -885,7 +2837,7 @@ func (s *serviceServer) serveInsertEventsProtobuf(ctx context.Context, resp http
 	callResponseSent(ctx, s.hooks)
 }
 
-func (s *serviceServer) serveDeleteEvents(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) serveSnapshotBuffer(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	header := req.Header.Get("Content-Type")
 	i := strings.Index(header, ";")
 	if i == -1 {
@@ -893,9 +2845,9 @@ func (s *serviceServer) serveDeleteEvents(ctx context.Context, resp http.Respons
 	}
 	switch strings.TrimSpace(strings.ToLower(header[:i])) {
 	case "application/json":
-		s.serveDeleteEventsJSON(ctx, resp, req)
+		s.serveSnapshotBufferJSON(ctx, resp, req)
 	case "application/protobuf":
-		s.serveDeleteEventsProtobuf(ctx, resp, req)
+		s.serveSnapshotBufferProtobuf(ctx, resp, req)
 	default:
 		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
 		twerr := badRouteError(msg, req.Method, req.URL.Path)
@@ -903,9 +2855,9 @@ func (s *serviceServer) serveDeleteEvents(ctx context.Context, resp http.Respons
 	}
 }
 
-func (s *serviceServer) serveDeleteEventsJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) serveSnapshotBufferJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	var err error
-	ctx = ctxsetters.WithMethodName(ctx, "DeleteEvents")
+	ctx = ctxsetters.WithMethodName(ctx, "SnapshotBuffer")
 	ctx, err = callRequestRouted(ctx, s.hooks)
 	if err != nil {
 		s.writeError(ctx, resp, err)
@@ -918,29 +2870,29 @@ func (s *serviceServer) serveDeleteEventsJSON(ctx context.Context, resp http.Res
 		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
 		return
 	}
-	reqContent := new(DeleteEventsRequest)
+	reqContent := new(SnapshotBufferRequest)
 	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
 	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
 		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
 		return
 	}
 
-	handler := s.Service.DeleteEvents
+	handler := s.Service.SnapshotBuffer
 	if s.interceptor != nil {
-		handler = func(ctx context.Context, req *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+		handler = func(ctx context.Context, req *SnapshotBufferRequest) (*SnapshotBufferResponse, error) {
 			resp, err := s.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*DeleteEventsRequest)
+					typedReq, ok := req.(*SnapshotBufferRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*DeleteEventsRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*SnapshotBufferRequest) when calling interceptor")
 					}
-					return s.Service.DeleteEvents(ctx, typedReq)
+					return s.Service.SnapshotBuffer(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*DeleteEventsResponse)
+				typedResp, ok := resp.(*SnapshotBufferResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*DeleteEventsResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*SnapshotBufferResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -949,7 +2901,7 @@ func (s *serviceServer) serveDeleteEventsJSON(ctx context.Context, resp http.Res
 	}
 
 	// Call service method
-	var respContent *DeleteEventsResponse
+	var respContent *SnapshotBufferResponse
 	func() {
 		defer ensurePanicResponses(ctx, resp, s.hooks)
 		respContent, err = handler(ctx, reqContent)
@@ -960,7 +2912,7 @@ func (s *serviceServer) serveDeleteEventsJSON(ctx context.Context, resp http.Res
 		return
 	}
 	if respContent == nil {
-		s.writeError(ctx, resp, twirp.InternalError("received a nil *DeleteEventsResponse and nil error while calling DeleteEvents. nil responses are not supported"))
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *SnapshotBufferResponse and nil error while calling SnapshotBuffer. nil responses are not supported"))
 		return
 	}
 
@@ -986,42 +2938,42 @@ func (s *serviceServer) serveDeleteEventsJSON(ctx context.Context, resp http.Res
 	callResponseSent(ctx, s.hooks)
 }
 
-func (s *serviceServer) serveDeleteEventsProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+func (s *serviceServer) serveSnapshotBufferProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
 	var err error
-	ctx = ctxsetters.WithMethodName(ctx, "DeleteEvents")
+	ctx = ctxsetters.WithMethodName(ctx, "SnapshotBuffer")
 	ctx, err = callRequestRouted(ctx, s.hooks)
 	if err != nil {
 		s.writeError(ctx, resp, err)
 		return
 	}
 
-	buf, err := ioutil.ReadAll(req.Body)
+	buf, err := io.ReadAll(req.Body)
 	if err != nil {
 		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
 		return
 	}
-	reqContent := new(DeleteEventsRequest)
+	reqContent := new(SnapshotBufferRequest)
 	if err = proto.Unmarshal(buf, reqContent); err != nil {
 		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
 		return
 	}
 
-	handler := s.Service.DeleteEvents
+	handler := s.Service.SnapshotBuffer
 	if s.interceptor != nil {
-		handler = func(ctx context.Context, req *DeleteEventsRequest) (*DeleteEventsResponse, error) {
+		handler = func(ctx context.Context, req *SnapshotBufferRequest) (*SnapshotBufferResponse, error) {
 			resp, err := s.interceptor(
 				func(ctx context.Context, req interface{}) (interface{}, error) {
-					typedReq, ok := req.(*DeleteEventsRequest)
+					typedReq, ok := req.(*SnapshotBufferRequest)
 					if !ok {
-						return nil, twirp.InternalError("failed type assertion req.(*DeleteEventsRequest) when calling interceptor")
+						return nil, twirp.InternalError("failed type assertion req.(*SnapshotBufferRequest) when calling interceptor")
 					}
-					return s.Service.DeleteEvents(ctx, typedReq)
+					return s.Service.SnapshotBuffer(ctx, typedReq)
 				},
 			)(ctx, req)
 			if resp != nil {
-				typedResp, ok := resp.(*DeleteEventsResponse)
+				typedResp, ok := resp.(*SnapshotBufferResponse)
 				if !ok {
-					return nil, twirp.InternalError("failed type assertion resp.(*DeleteEventsResponse) when calling interceptor")
+					return nil, twirp.InternalError("failed type assertion resp.(*SnapshotBufferResponse) when calling interceptor")
 				}
 				return typedResp, err
 			}
@@ -1030,7 +2982,7 @@ func (s *serviceServer) serveDeleteEventsProtobuf(ctx context.Context, resp http
 	}
 
 	// Call service method
-	var respContent *DeleteEventsResponse
+	var respContent *SnapshotBufferResponse
 	func() {
 		defer ensurePanicResponses(ctx, resp, s.hooks)
 		respContent, err = handler(ctx, reqContent)
@@ -1041,7 +2993,7 @@ func (s *serviceServer) serveDeleteEventsProtobuf(ctx context.Context, resp http
 		return
 	}
 	if respContent == nil {
-		s.writeError(ctx, resp, twirp.InternalError("received a nil *DeleteEventsResponse and nil error while calling DeleteEvents. nil responses are not supported"))
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *SnapshotBufferResponse and nil error while calling SnapshotBuffer. nil responses are not supported"))
 		return
 	}
 
@@ -1070,7 +3022,7 @@ func (s *serviceServer) ServiceDescriptor() ([]byte, int) {
 }
 
 func (s *serviceServer) ProtocGenTwirpVersion() string {
-	return "v8.1.2"
+	return "v8.1.3"
 }
 
 // PathPrefix returns the base service path, in the form: "/<prefix>/<package>.<Service>/"
@@ -1189,7 +3141,7 @@ func writeError(ctx context.Context, resp http.ResponseWriter, err error, hooks
 }
 
 // sanitizeBaseURL parses the the baseURL, and adds the "http" scheme if needed.
-// If the URL is unparsable, the baseURL is returned unchaged.
+// If the URL is unparsable, the baseURL is returned unchanged.
 func sanitizeBaseURL(baseURL string) string {
 	u, err := url.Parse(baseURL)
 	if err != nil {
@@ -1203,9 +3155,12 @@ func sanitizeBaseURL(baseURL string) string {
 
 // baseServicePath composes the path prefix for the service (without <Method>).
 // e.g.: baseServicePath("/twirp", "my.pkg", "MyService")
-//       returns => "/twirp/my.pkg.MyService/"
+//
+//	returns => "/twirp/my.pkg.MyService/"
+//
 // e.g.: baseServicePath("", "", "MyService")
-//       returns => "/MyService/"
+//
+//	returns => "/MyService/"
 func baseServicePath(prefix, pkg, service string) string {
 	fullServiceName := service
 	if pkg != "" {
@@ -1260,7 +3215,7 @@ func newRequest(ctx context.Context, url string, reqBody io.Reader, contentType
 	}
 	req.Header.Set("Accept", contentType)
 	req.Header.Set("Content-Type", contentType)
-	req.Header.Set("Twirp-Version", "v8.1.2")
+	req.Header.Set("Twirp-Version", "v8.1.3")
 	return req, nil
 }
 
@@ -1311,7 +3266,7 @@ func errorFromResponse(resp *http.Response) twirp.Error {
 		return twirpErrorFromIntermediary(statusCode, msg, location)
 	}
 
-	respBodyBytes, err := ioutil.ReadAll(resp.Body)
+	respBodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return wrapInternal(err, "failed to read server error response body")
 	}
@@ -1511,7 +3466,7 @@ func doProtobufRequest(ctx context.Context, client HTTPClient, hooks *twirp.Clie
 		return ctx, errorFromResponse(resp)
 	}
 
-	respBodyBytes, err := ioutil.ReadAll(resp.Body)
+	respBodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return ctx, wrapInternal(err, "failed to read response body")
 	}
@@ -1643,29 +3598,106 @@ func callClientError(ctx context.Context, h *twirp.ClientHooks, err twirp.Error)
 }
 
 var twirpFileDescriptor0 = []byte{
-	// 383 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x53, 0xc1, 0xae, 0x93, 0x40,
-	0x14, 0x0d, 0x16, 0xda, 0x7a, 0x5b, 0x13, 0x33, 0x90, 0x86, 0xb2, 0xb1, 0xc1, 0x98, 0xd4, 0x98,
-	0x80, 0xa9, 0xee, 0x74, 0x65, 0x64, 0x51, 0x77, 0xe2, 0xc2, 0xc4, 0x85, 0x06, 0xda, 0x2b, 0x4e,
-	0x2c, 0x33, 0x38, 0x33, 0x90, 0xf4, 0x13, 0xfd, 0xab, 0x17, 0x66, 0x20, 0x0f, 0x5e, 0x78, 0x9b,
-	0x97, 0xb7, 0x81, 0x39, 0xe7, 0xde, 0x39, 0xf7, 0xdc, 0x13, 0x00, 0xb7, 0x12, 0x5c, 0xf1, 0x58,
-	0xa2, 0x68, 0xe8, 0x09, 0x23, 0x8d, 0x88, 0x5d, 0x5e, 0xff, 0xf2, 0xe0, 0x45, 0xc1, 0x79, 0x71,
-	0xc1, 0x58, 0x73, 0x79, 0xfd, 0x3b, 0x56, 0xb4, 0x44, 0xa9, 0xb2, 0xb2, 0x32, 0x6d, 0x61, 0x02,
-	0x4e, 0xd2, 0x20, 0x53, 0x84, 0x80, 0xcd, 0xb2, 0x12, 0x7d, 0x6b, 0x67, 0xed, 0x9f, 0xa6, 0xfa,
-	0xdc, 0x72, 0x35, 0xa3, 0xca, 0x9f, 0x19, 0xae, 0x3d, 0x13, 0x0f, 0x9c, 0x26, 0xbb, 0xd4, 0xe8,
-	0xdb, 0x3b, 0x6b, 0x6f, 0xa5, 0x06, 0x84, 0x08, 0x4e, 0xc2, 0x94, 0xb8, 0x92, 0x2d, 0x2c, 0x95,
-	0xc8, 0x4e, 0xf8, 0x8b, 0x9e, 0x3b, 0xa9, 0x85, 0xc6, 0xc7, 0x33, 0xd9, 0xc0, 0x9c, 0x0b, 0x5a,
-	0x50, 0xe6, 0x3f, 0xd1, 0x85, 0x0e, 0x91, 0x97, 0x30, 0xc7, 0xd6, 0x82, 0xf4, 0xed, 0xdd, 0x6c,
-	0xbf, 0x3a, 0xac, 0xa2, 0xd6, 0x7a, 0xa4, 0x6d, 0xa5, 0x5d, 0xe9, 0x8b, 0xbd, 0x9c, 0x3d, 0xb7,
-	0xc3, 0xef, 0xb0, 0xfe, 0x5a, 0xa3, 0xb8, 0xa6, 0xf8, 0xaf, 0x46, 0xa9, 0x1e, 0x32, 0xcd, 0x03,
-	0x47, 0x4b, 0x76, 0x4b, 0x19, 0x10, 0xbe, 0x87, 0x67, 0x9d, 0xb0, 0xac, 0x38, 0x93, 0x38, 0x30,
-	0x65, 0xdd, 0x6b, 0x2a, 0xfc, 0x08, 0xee, 0x91, 0x49, 0x14, 0x4a, 0xd3, 0xb2, 0x77, 0xf5, 0x0a,
-	0x16, 0xc8, 0x94, 0xa0, 0x78, 0xf7, 0x72, 0x9b, 0x50, 0xda, 0xd7, 0xc2, 0x0d, 0x78, 0xe3, 0xdb,
-	0x66, 0x74, 0xf8, 0x13, 0xdc, 0xcf, 0x78, 0x41, 0x85, 0x63, 0xd5, 0x47, 0xdb, 0x75, 0x03, 0xde,
-	0x58, 0xdf, 0xcc, 0x3d, 0xfc, 0xb7, 0x60, 0xf1, 0xcd, 0x7c, 0x43, 0xe4, 0x2d, 0x38, 0x3a, 0x0f,
-	0x42, 0x8c, 0xf5, 0x61, 0xea, 0x81, 0x3b, 0xe2, 0xba, 0xc0, 0x12, 0x58, 0x0f, 0xb7, 0x21, 0x5b,
-	0xd3, 0x34, 0x91, 0x4f, 0x10, 0x4c, 0x95, 0x6e, 0x65, 0x86, 0xe6, 0x7a, 0x99, 0x89, 0x40, 0x7a,
-	0x99, 0xa9, 0x5d, 0x3e, 0xbd, 0xf9, 0xf1, 0xba, 0xa0, 0xea, 0x4f, 0x9d, 0x47, 0x27, 0x5e, 0xc6,
-	0x6d, 0xdf, 0x19, 0x1b, 0xfd, 0x36, 0xbf, 0x82, 0x3e, 0x7e, 0x68, 0x1f, 0x55, 0x9e, 0xcf, 0x35,
-	0xf5, 0xee, 0x26, 0x00, 0x00, 0xff, 0xff, 0x1a, 0x2e, 0x39, 0xae, 0x48, 0x03, 0x00, 0x00,
+	// 1604 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x58, 0xdb, 0x72, 0x23, 0xb7,
+	0x11, 0xf5, 0xf0, 0xce, 0xd6, 0x85, 0x5c, 0xe8, 0x36, 0xe2, 0x6a, 0xbd, 0xf2, 0xb8, 0xe2, 0xc8,
+	0x4e, 0x42, 0xad, 0x37, 0x95, 0x87, 0x54, 0x52, 0x76, 0x2d, 0x77, 0xe5, 0x68, 0xe3, 0x5c, 0xec,
+	0xd1, 0xc6, 0xa9, 0xe4, 0x65, 0x6a, 0x38, 0x03, 0x91, 0x28, 0x93, 0xc0, 0x04, 0xc0, 0x50, 0xd1,
+	0x1f, 0xf8, 0x35, 0xf9, 0x82, 0x7c, 0x8f, 0x7f, 0x21, 0x55, 0x79, 0xc9, 0x7f, 0xa4, 0x52, 0x68,
+	0x60, 0xc8, 0x21, 0x35, 0xf2, 0x6e, 0xb9, 0xf6, 0xc1, 0x2f, 0xd2, 0xe0, 0x9c, 0x46, 0xa3, 0xd1,
+	0x38, 0xdd, 0x80, 0x04, 0x7b, 0x99, 0x14, 0x5a, 0x9c, 0x2b, 0x2a, 0x17, 0x2c, 0xa1, 0x43, 0x1c,
+	0x91, 0xc6, 0xfc, 0xf6, 0x6b, 0x31, 0x78, 0x3c, 0x11, 0x62, 0x32, 0xa3, 0xe7, 0x88, 0x8d, 0xf3,
+	0xeb, 0x73, 0xcd, 0xe6, 0x54, 0xe9, 0x78, 0x9e, 0x59, 0xb3, 0xe0, 0xbf, 0x1e, 0x34, 0x2f, 0x16,
+	0x94, 0x6b, 0x42, 0xa0, 0xc1, 0xe3, 0x39, 0xf5, 0xbd, 0x53, 0xef, 0xac, 0x1b, 0xe2, 0xb7, 0xc1,
+	0x72, 0xce, 0xb4, 0x5f, 0xb7, 0x98, 0xf9, 0x26, 0xfb, 0xd0, 0x5c, 0xc4, 0xb3, 0x9c, 0xfa, 0x8d,
+	0x53, 0xef, 0xcc, 0x0b, 0xed, 0xc0, 0xa0, 0x89, 0xc8, 0xb9, 0xf6, 0x9b, 0xa7, 0xde, 0x59, 0x3d,
+	0xb4, 0x03, 0x72, 0x0a, 0x5d, 0xc6, 0x75, 0x64, 0xed, 0x5b, 0x86, 0xb9, 0x7c, 0x27, 0xec, 0x30,
+	0xae, 0xbf, 0x32, 0xc8, 0x37, 0x9e, 0x47, 0x1e, 0x43, 0x67, 0x11, 0x4b, 0x16, 0xf3, 0x84, 0xfa,
+	0x6d, 0xe3, 0xf0, 0xd2, 0x0b, 0x97, 0x88, 0x31, 0x38, 0x81, 0xb6, 0xd2, 0x69, 0x94, 0xd2, 0x85,
+	0xdf, 0x41, 0xbe, 0x16, 0xb6, 0x94, 0x4e, 0x5f, 0xd0, 0xc5, 0x37, 0x9e, 0x37, 0xda, 0x06, 0x88,
+	0x96, 0x2b, 0x8c, 0xb6, 0xa0, 0x1b, 0x15, 0x73, 0x47, 0x00, 0x9d, 0xc8, 0xcd, 0x0c, 0x28, 0x34,
+	0x2f, 0xb8, 0x96, 0xb7, 0xe4, 0x18, 0x3a, 0x5a, 0xc6, 0x09, 0x8d, 0x58, 0xea, 0x36, 0xda, 0xc6,
+	0xf1, 0xcb, 0x94, 0x1c, 0x42, 0x4b, 0x48, 0x36, 0x61, 0xdc, 0xaf, 0x21, 0xe1, 0x46, 0xe4, 0x7d,
+	0x68, 0x51, 0x93, 0x20, 0xe5, 0x37, 0x4e, 0xeb, 0x67, 0x5b, 0x4f, 0xb7, 0x86, 0x26, 0xb3, 0x43,
+	0x4c, 0x5a, 0xe8, 0xa8, 0xdf, 0x36, 0x3a, 0xf5, 0x7e, 0x23, 0xf8, 0xb6, 0x09, 0xdb, 0x5f, 0xe6,
+	0x54, 0xde, 0x86, 0xf4, 0x6f, 0x39, 0x55, 0xfa, 0xfb, 0x2c, 0xb7, 0x0f, 0x4d, 0xf4, 0xe9, 0x72,
+	0x6e, 0x07, 0xe4, 0x43, 0xe8, 0x4f, 0x99, 0xd2, 0x62, 0x22, 0xe3, 0x79, 0x34, 0x16, 0x39, 0x4f,
+	0x6d, 0x38, 0x5e, 0xd8, 0x5b, 0xe2, 0x23, 0x84, 0xc9, 0x23, 0x80, 0x1b, 0xa6, 0xa7, 0xd1, 0xea,
+	0x38, 0x3a, 0x61, 0xd7, 0x20, 0xcf, 0xf1, 0x48, 0x0a, 0x5a, 0xe9, 0x58, 0x2b, 0xbf, 0xb5, 0xa2,
+	0xaf, 0x0c, 0x40, 0x7e, 0x09, 0xa0, 0x74, 0x2c, 0x75, 0x64, 0x84, 0x82, 0x27, 0xb2, 0xf5, 0x74,
+	0x30, 0xb4, 0x2a, 0x1a, 0x16, 0x2a, 0x1a, 0xbe, 0x2a, 0x54, 0x14, 0x76, 0xd1, 0xda, 0x8c, 0xc9,
+	0x2f, 0xa0, 0x43, 0x79, 0x6a, 0x27, 0x76, 0x5e, 0x3b, 0xb1, 0x4d, 0x79, 0x8a, 0xd3, 0xce, 0xa1,
+	0x33, 0x8e, 0x15, 0x9d, 0x31, 0x4e, 0xfd, 0x2e, 0x4e, 0xdb, 0xb3, 0x19, 0xc6, 0x4c, 0x8e, 0x1c,
+	0x15, 0x2e, 0x8d, 0x4c, 0x52, 0x67, 0x22, 0x89, 0x67, 0x51, 0x9a, 0xf8, 0x80, 0xf1, 0xb7, 0x71,
+	0xfc, 0x22, 0x21, 0x1f, 0x40, 0x2f, 0x11, 0x7c, 0x41, 0x4d, 0xfc, 0x22, 0x42, 0xe9, 0x6e, 0x61,
+	0x1a, 0x77, 0x1c, 0xfc, 0x4a, 0xfc, 0xc9, 0x68, 0xf8, 0xc7, 0xd0, 0x8b, 0x67, 0x33, 0x71, 0x13,
+	0x5d, 0xb3, 0x99, 0xa6, 0x92, 0xf1, 0x89, 0xbf, 0x8d, 0x9e, 0x76, 0x11, 0xfe, 0xac, 0x40, 0xc9,
+	0x43, 0xe8, 0x66, 0x54, 0x46, 0x78, 0x68, 0xfe, 0x0e, 0x9a, 0x74, 0x32, 0x2a, 0x5f, 0x99, 0x31,
+	0x39, 0x83, 0xbe, 0xa4, 0x71, 0x1a, 0xdd, 0x8a, 0x5c, 0x46, 0x37, 0x92, 0x69, 0xaa, 0xfc, 0x5d,
+	0xeb, 0xc6, 0xe0, 0x7f, 0x11, 0xb9, 0xfc, 0x33, 0xa2, 0xe4, 0x04, 0xba, 0x5f, 0xd3, 0x5b, 0x95,
+	0xc5, 0x09, 0x55, 0x7e, 0xef, 0xb4, 0x7e, 0xd6, 0x0d, 0x57, 0x80, 0x89, 0x26, 0x93, 0x74, 0xc1,
+	0x44, 0xae, 0xa2, 0x8c, 0x4a, 0x26, 0x52, 0xbf, 0x6f, 0xdd, 0x14, 0xf0, 0x17, 0x88, 0x92, 0xf7,
+	0x61, 0x07, 0xcf, 0x6e, 0x59, 0x31, 0x0f, 0xd0, 0x6c, 0xdb, 0x80, 0x5f, 0x39, 0xcc, 0x84, 0x8c,
+	0x46, 0xd3, 0x58, 0x4d, 0x7d, 0x62, 0x43, 0x36, 0xc0, 0x65, 0xac, 0xa6, 0x46, 0x47, 0x58, 0x2a,
+	0x11, 0xfd, 0x7b, 0x26, 0xa9, 0x52, 0x4c, 0x70, 0x7f, 0x0f, 0x33, 0xd4, 0x43, 0xfc, 0x62, 0x09,
+	0x07, 0xdf, 0x7a, 0xb0, 0xb3, 0x76, 0x04, 0x6f, 0x4f, 0xcd, 0xeb, 0x22, 0x6b, 0x7c, 0x5f, 0x91,
+	0x35, 0xdf, 0x58, 0x64, 0xc1, 0xbf, 0xeb, 0x6e, 0x33, 0x21, 0x55, 0x99, 0xe0, 0x8a, 0x96, 0xca,
+	0xda, 0xbb, 0xb7, 0xac, 0xc9, 0x13, 0xe8, 0x2e, 0xcb, 0xcb, 0xaf, 0xa1, 0x1d, 0xb1, 0x76, 0x97,
+	0xcb, 0xaa, 0x63, 0x3c, 0x5c, 0x19, 0x91, 0x0f, 0xa0, 0x69, 0x2b, 0xab, 0x8e, 0xc1, 0xf5, 0x4b,
+	0x52, 0xc6, 0x02, 0x0b, 0x2d, 0x6d, 0x0e, 0xe2, 0x3a, 0x66, 0x33, 0x9a, 0x46, 0x2b, 0x61, 0x34,
+	0x50, 0x18, 0x3d, 0x8b, 0x7f, 0x5e, 0xc0, 0xe4, 0x53, 0xd8, 0xa1, 0xb1, 0x9c, 0x31, 0xaa, 0xf4,
+	0x9b, 0xee, 0x7b, 0xbb, 0x98, 0x80, 0x39, 0xfb, 0x29, 0x90, 0x55, 0xba, 0xcd, 0xc9, 0x33, 0x49,
+	0x53, 0x57, 0xfa, 0xfd, 0x65, 0x6a, 0x2f, 0x2c, 0x4e, 0x3e, 0xb9, 0xab, 0xc6, 0x36, 0xee, 0xfc,
+	0xa0, 0x94, 0xa1, 0xe7, 0x62, 0x9e, 0xc5, 0x92, 0x29, 0xc1, 0xef, 0x88, 0x94, 0x40, 0x03, 0xa5,
+	0xd7, 0xb1, 0x77, 0x86, 0xf9, 0x26, 0x1f, 0xc3, 0x7e, 0x4a, 0x27, 0x32, 0x4e, 0x69, 0x1a, 0x25,
+	0x82, 0x2b, 0xa6, 0x34, 0xe5, 0xc9, 0x2d, 0xd6, 0x7b, 0x27, 0xdc, 0x2b, 0xb8, 0xe7, 0x2b, 0x8a,
+	0x7c, 0x04, 0x0f, 0xb8, 0xe0, 0xd1, 0x35, 0xe3, 0x4c, 0x53, 0xdb, 0xdf, 0x15, 0x96, 0x7b, 0x3d,
+	0xec, 0x71, 0xc1, 0x3f, 0x43, 0x1c, 0xaf, 0x11, 0x15, 0xfc, 0xcb, 0x03, 0x58, 0xa5, 0x98, 0xbc,
+	0x07, 0xdb, 0x52, 0xdc, 0xa8, 0x48, 0x25, 0x31, 0xe7, 0xd4, 0x6a, 0xb5, 0x1e, 0x6e, 0x19, 0xec,
+	0xca, 0x42, 0xa6, 0xe4, 0x26, 0x52, 0xe4, 0x99, 0x8a, 0x24, 0xd5, 0xb9, 0x34, 0x56, 0x35, 0xb4,
+	0xda, 0xb5, 0x70, 0xe8, 0xd0, 0xaa, 0x4e, 0x51, 0xaf, 0xec, 0x14, 0x8f, 0x00, 0x66, 0x31, 0x86,
+	0x1e, 0xcd, 0x95, 0xbb, 0x1b, 0xbb, 0x0e, 0xf9, 0xbd, 0x0a, 0xae, 0x61, 0xbb, 0x2c, 0x19, 0xf2,
+	0x18, 0xb6, 0x66, 0xe2, 0x86, 0x4a, 0xdb, 0xcc, 0x31, 0x44, 0x2f, 0x04, 0x84, 0xb0, 0x8f, 0x1b,
+	0x83, 0x3c, 0xcb, 0x96, 0x06, 0x35, 0x6b, 0x80, 0x90, 0x35, 0x58, 0xde, 0xb8, 0xf5, 0xd2, 0x8d,
+	0x1b, 0x0c, 0xe1, 0x01, 0x66, 0x02, 0x3b, 0xd4, 0xeb, 0xaf, 0xa1, 0xe0, 0x13, 0x20, 0x65, 0x7b,
+	0x57, 0x1c, 0x67, 0x1b, 0xc5, 0xe1, 0x64, 0x8c, 0x46, 0x6b, 0x15, 0x12, 0xfc, 0xd3, 0x03, 0x58,
+	0xc1, 0xa5, 0x3e, 0xe0, 0xad, 0xf5, 0x81, 0xf7, 0x8a, 0x3e, 0x50, 0x3b, 0xf5, 0x36, 0x8b, 0x6d,
+	0xd5, 0x14, 0x12, 0x49, 0x63, 0x4d, 0xd3, 0x28, 0xd6, 0x7e, 0xfd, 0xb5, 0x1a, 0xef, 0x3a, 0xeb,
+	0x67, 0x9a, 0xec, 0x42, 0x8d, 0xa5, 0x98, 0xf3, 0x6e, 0x58, 0x63, 0x69, 0xf0, 0x3f, 0x0f, 0x0e,
+	0xac, 0x42, 0xe9, 0x1f, 0x71, 0x7d, 0x55, 0xca, 0x84, 0x8d, 0x28, 0x8a, 0x8b, 0x4c, 0xd8, 0xf1,
+	0xb3, 0x12, 0x35, 0xf6, 0x6b, 0x65, 0x6a, 0xf4, 0x43, 0xe9, 0x62, 0x55, 0x62, 0x6c, 0x55, 0x89,
+	0x31, 0xf8, 0x0d, 0x1c, 0x6e, 0xee, 0xdf, 0x9d, 0xec, 0xcf, 0x36, 0x4e, 0xf6, 0x9e, 0xa2, 0x2e,
+	0x8e, 0xf7, 0x1f, 0x35, 0xe8, 0x6d, 0x70, 0xdf, 0xf9, 0x50, 0xac, 0x95, 0x1e, 0x8a, 0x27, 0xd0,
+	0xb6, 0x77, 0x4d, 0x8c, 0x79, 0xf3, 0x2e, 0xdf, 0x09, 0x5b, 0x08, 0x3c, 0x73, 0xef, 0x3a, 0xcb,
+	0x8e, 0x6d, 0xb1, 0x5c, 0x7a, 0x8e, 0x1d, 0x19, 0xf6, 0x5d, 0x80, 0x94, 0x5d, 0x5f, 0x53, 0x49,
+	0x79, 0x62, 0x53, 0xe4, 0x85, 0x25, 0x84, 0x1c, 0x43, 0x53, 0xc6, 0x9a, 0x09, 0xbf, 0xe5, 0xde,
+	0x84, 0x76, 0x68, 0xa6, 0x7e, 0x04, 0xbb, 0x19, 0x95, 0x09, 0xe5, 0x3a, 0x4a, 0xa6, 0x31, 0x9f,
+	0x14, 0xef, 0xca, 0x7a, 0xb8, 0xe3, 0xf0, 0xe7, 0x08, 0x9b, 0xe7, 0xa3, 0x79, 0x23, 0xba, 0x18,
+	0x4b, 0xdf, 0xe3, 0x51, 0x07, 0x5a, 0x11, 0x3a, 0x1c, 0x3d, 0x80, 0x5e, 0xb4, 0xee, 0x2e, 0xf8,
+	0x35, 0xec, 0xbd, 0xe4, 0x8a, 0x4a, 0x8d, 0x89, 0x59, 0x4a, 0xeb, 0x47, 0xd0, 0xa6, 0x5c, 0x4b,
+	0x46, 0x37, 0x6f, 0x14, 0xf3, 0xf0, 0x0c, 0x0b, 0x2e, 0x38, 0x84, 0xfd, 0xf5, 0xd9, 0xf6, 0x60,
+	0x82, 0x0c, 0xf6, 0x5e, 0xd0, 0x19, 0xd5, 0x74, 0xdd, 0xeb, 0x5b, 0xbb, 0x73, 0xfb, 0x50, 0x67,
+	0x69, 0x71, 0xc7, 0x98, 0xcf, 0xe0, 0x09, 0xec, 0xaf, 0xaf, 0xe8, 0x24, 0xe2, 0x43, 0x3b, 0x45,
+	0xbc, 0xe8, 0x9c, 0xc5, 0x30, 0xc8, 0x60, 0x3f, 0xa4, 0xd9, 0x2c, 0x4e, 0x36, 0x82, 0xfc, 0x18,
+	0x5a, 0xd6, 0x04, 0x27, 0x6c, 0x3d, 0x3d, 0xb6, 0x3b, 0xaf, 0xd8, 0x4f, 0xe8, 0x0c, 0xcb, 0xd9,
+	0xaa, 0x7d, 0x47, 0xb6, 0x8e, 0xe0, 0x60, 0x63, 0x45, 0x97, 0xae, 0x63, 0x38, 0xfa, 0x1d, 0x53,
+	0x3a, 0xa4, 0xe6, 0x64, 0xd6, 0x96, 0x08, 0x9e, 0x81, 0x7f, 0x97, 0x72, 0x7b, 0x7b, 0xc3, 0x43,
+	0x3a, 0x82, 0x83, 0x2f, 0xe2, 0x5c, 0xd1, 0x97, 0x7c, 0x42, 0x95, 0x66, 0x82, 0x17, 0xbe, 0x7d,
+	0x38, 0xdc, 0x24, 0x5c, 0x40, 0x3e, 0x1c, 0x86, 0x54, 0xe5, 0xf3, 0xbb, 0x73, 0x8e, 0xe1, 0xe8,
+	0x0e, 0xe3, 0x26, 0x1d, 0xc1, 0xc1, 0x15, 0x8f, 0x33, 0x35, 0x15, 0x7a, 0x94, 0x1b, 0x71, 0x17,
+	0x73, 0x5e, 0xc0, 0xe1, 0x26, 0xe1, 0x76, 0x40, 0xa0, 0x91, 0xc5, 0x7a, 0x5a, 0x54, 0x9f, 0xf9,
+	0x36, 0x4a, 0x70, 0x45, 0x6d, 0x2f, 0x31, 0x37, 0x7a, 0xfa, 0x9f, 0x26, 0xb4, 0xaf, 0xec, 0x5f,
+	0x85, 0xe4, 0x09, 0x34, 0xb1, 0xd1, 0x13, 0x52, 0x7a, 0x92, 0xb8, 0xe5, 0x06, 0x7b, 0x6b, 0x98,
+	0x5b, 0xe9, 0x53, 0x77, 0xa9, 0xda, 0xc7, 0xee, 0x51, 0xc9, 0xa4, 0x7c, 0xb9, 0x0c, 0xfc, 0xbb,
+	0x84, 0x73, 0xf0, 0x39, 0xec, 0xae, 0x77, 0x21, 0xf2, 0xd0, 0xda, 0x56, 0xf6, 0xe6, 0xc1, 0x49,
+	0x35, 0xe9, 0x9c, 0x5d, 0xc0, 0x76, 0xb9, 0x6e, 0x88, 0xd3, 0x58, 0x45, 0x25, 0x0e, 0x06, 0x55,
+	0xd4, 0xca, 0x4d, 0x59, 0x96, 0xe4, 0x7e, 0xa9, 0x0e, 0x06, 0x55, 0x94, 0x73, 0x73, 0x09, 0x3b,
+	0x6b, 0xba, 0x24, 0xce, 0xb8, 0xaa, 0x3c, 0x06, 0x0f, 0x2b, 0x39, 0xe7, 0xe9, 0x4b, 0xe8, 0x6f,
+	0xaa, 0x95, 0x3c, 0xb2, 0x13, 0xee, 0x11, 0xf8, 0xe0, 0xdd, 0xfb, 0xe8, 0x55, 0xde, 0xd7, 0x45,
+	0x5a, 0xe4, 0xbd, 0x52, 0xd3, 0x83, 0x93, 0x6a, 0xd2, 0x39, 0xfb, 0x03, 0xf4, 0x36, 0xd4, 0x4b,
+	0x4e, 0x8a, 0xfd, 0x54, 0xc9, 0x7d, 0xf0, 0xe8, 0x1e, 0x76, 0x15, 0xdc, 0xba, 0xb2, 0x8b, 0xe0,
+	0x2a, 0x0b, 0x61, 0x70, 0x52, 0x4d, 0x5a, 0x67, 0xa3, 0x9f, 0xfc, 0xf5, 0xc3, 0x09, 0xd3, 0xd3,
+	0x7c, 0x3c, 0x4c, 0xc4, 0xfc, 0xdc, 0x58, 0xa6, 0x74, 0x81, 0xbf, 0xed, 0x7f, 0x3c, 0xf0, 0xf3,
+	0x57, 0xe6, 0x47, 0x36, 0x1e, 0xb7, 0x10, 0xfa, 0xf9, 0xff, 0x07, 0x00, 0xca, 0xe7, 0x0b, 0x51,
+	0x2f, 0x11, 0x00, 0x00,
 }
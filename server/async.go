@@ -0,0 +1,89 @@
+package server
+
+import (
+	"log"
+	"sync"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+var (
+	errAsyncQueueFull = twirp.NewError(twirp.ResourceExhausted, "too many async inserts waiting")
+	errShuttingDown   = twirp.NewError(twirp.Unavailable, "server is shutting down")
+)
+
+// asyncInserter inserts the events of the async requests after
+// they are responded to, with a fixed number of workers and a
+// bounded queue of requests waiting for them.
+type asyncInserter struct {
+	server *Server
+	queue  chan *pb.InsertEventsRequest
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex // held while sending to queue
+	closed bool
+}
+
+func newAsyncInserter(s *Server, workers, queueSize int) *asyncInserter {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	a := &asyncInserter{
+		server: s,
+		queue:  make(chan *pb.InsertEventsRequest, queueSize),
+	}
+	a.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go a.run()
+	}
+	return a
+}
+
+func (a *asyncInserter) run() {
+	defer a.wg.Done()
+	for req := range a.queue {
+		a.server.metrics.queuedAsyncInserts.Add(-1)
+		if err := a.server.insertEvents(writeModeBuffered, req); err != nil {
+			log.Printf("Failed to insert events asynchronously: %v", err)
+			a.server.metrics.asyncInsertErrors.Add(1)
+		}
+	}
+}
+
+// enqueue queues the request for a worker. It returns
+// errAsyncQueueFull if the queue is full, errShuttingDown
+// if the inserter is closed.
+func (a *asyncInserter) enqueue(req *pb.InsertEventsRequest) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return errShuttingDown
+	}
+	a.server.metrics.queuedAsyncInserts.Add(1)
+	select {
+	case a.queue <- req:
+		return nil
+	default:
+		a.server.metrics.queuedAsyncInserts.Add(-1)
+		a.server.metrics.rejectedAsyncInserts.Add(1)
+		return errAsyncQueueFull
+	}
+}
+
+// close stops accepting requests and waits
+// for the queued ones to be inserted.
+func (a *asyncInserter) close() {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	a.wg.Wait()
+}
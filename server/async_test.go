@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// blockStage blocks the ingestion until release is closed,
+// started receives each time it starts processing.
+type blockStage struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockStage) Process(entries []*pb.Entry) ([]*pb.Entry, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return entries, nil
+}
+
+func TestAsyncInserts(t *testing.T) {
+	session := newFakeSession()
+	cfg := testConfig()
+	cfg.IngestConfig.AsyncWorkers = 1
+	cfg.IngestConfig.AsyncQueueSize = 1
+	stage := &blockStage{started: make(chan struct{}, 3), release: make(chan struct{})}
+	s := newTestServer(t, cfg, session, WithStage("block", stage))
+
+	ctx := context.WithValue(context.Background(), writeModeKey{}, writeModeAsync)
+	insert := func(i int) error {
+		_, err := s.InsertEvents(ctx, &pb.InsertEventsRequest{Entries: []*pb.Entry{{
+			Origin: "o",
+			Events: []*pb.Event{{Name: fmt.Sprintf("e%d", i), Value: 1}},
+		}}})
+		return err
+	}
+
+	if err := insert(1); err != nil {
+		t.Fatalf("InsertEvents() = %v", err)
+	}
+	<-stage.started // the worker is busy
+	if err := insert(2); err != nil {
+		t.Fatalf("InsertEvents() = %v, want it queued", err)
+	}
+	if err := insert(3); errorCode(err) != twirp.ResourceExhausted {
+		t.Fatalf("InsertEvents() = %v, want code %q", err, twirp.ResourceExhausted)
+	}
+
+	close(stage.release)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if n := len(session.executed("INSERT")); n != 2 {
+		t.Errorf("Close() wrote %d rows, want the 2 accepted", n)
+	}
+	if err := insert(4); errorCode(err) != twirp.Unavailable {
+		t.Errorf("InsertEvents() after Close() = %v, want code %q", err, twirp.Unavailable)
+	}
+}
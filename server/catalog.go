@@ -0,0 +1,59 @@
+package server
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+const (
+	catalogStrict  = "strict"
+	catalogLenient = "lenient"
+)
+
+// checkCatalog checks the inserted events against the event
+// catalog. Violations are rejected in strict mode, logged in
+// lenient mode.
+func (s *Server) checkCatalog(entries []*pb.Entry) error {
+	if s.catalogMode == "" {
+		return nil
+	}
+	for _, entry := range entries {
+		for _, event := range entry.Events {
+			err := s.catalogViolation(event)
+			if err == nil {
+				continue
+			}
+			s.metrics.catalogViolations.Add(1)
+			if s.catalogMode == catalogStrict {
+				return twirp.NewError(twirp.InvalidArgument, err.Error())
+			}
+			log.Printf("Accepting event not conforming to the catalog: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) catalogViolation(event *pb.Event) error {
+	c, ok := s.catalog[event.Name]
+	if !ok {
+		return fmt.Errorf("unknown event %q", event.Name)
+	}
+	if event.Unit != c.Unit {
+		return fmt.Errorf("event %q has unit %q, want %q", event.Name, event.Unit, c.Unit)
+	}
+	switch c.Type {
+	case "int":
+		if event.IntValue == nil || event.Value != 0 {
+			return fmt.Errorf("event %q needs an integer value", event.Name)
+		}
+	case "float":
+		if event.IntValue != nil {
+			return fmt.Errorf("event %q needs a floating point value", event.Name)
+		}
+	}
+	return nil
+}
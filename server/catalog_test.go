@@ -0,0 +1,71 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/twitchtv/twirp"
+
+	"github.com/mykodev/myko/config"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestCheckCatalog(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *pb.Event
+		valid bool
+	}{
+		{name: "conforming", event: &pb.Event{Name: "http", Value: 1, Unit: "ms"}, valid: true},
+		{name: "conforming int", event: &pb.Event{Name: "count", IntValue: int64p(1)}, valid: true},
+		{name: "conforming float", event: &pb.Event{Name: "ratio", Value: 0.5}, valid: true},
+		{name: "untyped int", event: &pb.Event{Name: "http", IntValue: int64p(1), Unit: "ms"}, valid: true},
+		{name: "unknown event", event: &pb.Event{Name: "other", Value: 1}},
+		{name: "wrong unit", event: &pb.Event{Name: "http", Value: 1, Unit: "s"}},
+		{name: "float for int", event: &pb.Event{Name: "count", Value: 1}},
+		{name: "int and float for int", event: &pb.Event{Name: "count", Value: 1, IntValue: int64p(1)}},
+		{name: "int for float", event: &pb.Event{Name: "ratio", IntValue: int64p(1)}},
+	}
+	for _, mode := range []string{catalogStrict, catalogLenient} {
+		for _, tt := range tests {
+			t.Run(mode+"/"+tt.name, func(t *testing.T) {
+				cfg := testConfig()
+				cfg.IngestConfig.CatalogMode = mode
+				cfg.IngestConfig.Catalog = map[string]config.CatalogEvent{
+					"http":  {Unit: "ms"},
+					"count": {Type: "int"},
+					"ratio": {Type: "float"},
+				}
+				s := newTestServer(t, cfg, newFakeSession())
+
+				err := s.checkCatalog([]*pb.Entry{{Origin: "o", Events: []*pb.Event{tt.event}}})
+				wantCode := twirp.ErrorCode("")
+				if !tt.valid && mode == catalogStrict {
+					wantCode = twirp.InvalidArgument
+				}
+				if code := errorCode(err); code != wantCode || (code == "" && err != nil) {
+					t.Errorf("checkCatalog() = %v, want code %q", err, wantCode)
+				}
+				var wantViolations int64
+				if !tt.valid {
+					wantViolations = 1
+				}
+				if got := s.metrics.catalogViolations.Value(); got != wantViolations {
+					t.Errorf("catalog violations = %d, want %d", got, wantViolations)
+				}
+			})
+		}
+	}
+}
+
+func TestCheckCatalogDisabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.IngestConfig.Catalog = map[string]config.CatalogEvent{"http": {Unit: "ms"}}
+	s := newTestServer(t, cfg, newFakeSession())
+	if err := s.checkCatalog([]*pb.Entry{{Events: []*pb.Event{{Name: "other"}}}}); err != nil {
+		t.Errorf("checkCatalog() = %v without a catalog mode, want nil", err)
+	}
+	if got := s.metrics.catalogViolations.Value(); got != 0 {
+		t.Errorf("catalog violations = %d without a catalog mode, want 0", got)
+	}
+}
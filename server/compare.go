@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mykodev/myko/datastore/cassandra"
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// CompareOrigins aggregates the events of two origins in the
+// same window and pairs them by event name and unit. Difference
+// is value_b - value_a, ratio is value_b / value_a and percent
+// change is the relative change from value_a to value_b. Events
+// recorded by only one of the origins have no value for the
+// other one and no ratio or percent change.
+func (s *Server) CompareOrigins(ctx context.Context, req *pb.CompareOriginsRequest) (*pb.CompareOriginsResponse, error) {
+	if req.OriginA == "" {
+		return nil, twirp.RequiredArgumentError("origin_a")
+	}
+	if req.OriginB == "" {
+		return nil, twirp.RequiredArgumentError("origin_b")
+	}
+
+	var filters [2]cassandra.Filter
+	for i, origin := range []string{req.OriginA, req.OriginB} {
+		f, err := cassandra.NewFilter().
+			WithOrigin(origin).
+			WithEvent(s.normalizeName(req.Event)).
+			WithTimeRange(timeOf(req.StartTime), timeOf(req.EndTime)).
+			Build()
+		if err != nil {
+			return nil, twirp.NewError(twirp.InvalidArgument, err.Error())
+		}
+		if f.NeedsFiltering() && !req.AllowFiltering {
+			return nil, twirp.NewError(twirp.FailedPrecondition,
+				"query may scan the entire table, set allow_filtering to run it")
+		}
+		filters[i] = f
+	}
+
+	a, err := s.aggregate(scanOptions{ctx: ctx}, filters[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.aggregate(scanOptions{ctx: ctx}, filters[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.CompareOriginsResponse{Events: compareEvents(a, b)}, nil
+}
+
+// compareEvents pairs the events of a and b by name and unit.
+func compareEvents(a, b map[string]*pb.Event) []*pb.EventComparison {
+	comparisons := make(map[string]*pb.EventComparison)
+	get := func(e *pb.Event) *pb.EventComparison {
+		k := groupKey(e.Name, e.Unit)
+		c, ok := comparisons[k]
+		if !ok {
+			c = &pb.EventComparison{Name: e.Name, Unit: e.Unit}
+			comparisons[k] = c
+		}
+		return c
+	}
+	for _, e := range a {
+		v := totalValue(e)
+		get(e).ValueA = &v
+	}
+	for _, e := range b {
+		v := totalValue(e)
+		get(e).ValueB = &v
+	}
+
+	events := make([]*pb.EventComparison, 0, len(comparisons))
+	for _, c := range comparisons {
+		c.Difference = c.GetValueB() - c.GetValueA()
+		if c.ValueA != nil && c.ValueB != nil && *c.ValueA != 0 {
+			ratio := *c.ValueB / *c.ValueA
+			change := (ratio - 1) * 100
+			c.Ratio = &ratio
+			c.PercentChange = &change
+		}
+		events = append(events, c)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Name != events[j].Name {
+			return events[i].Name < events[j].Name
+		}
+		return events[i].Unit < events[j].Unit
+	})
+	return events
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestCompareOrigins(t *testing.T) {
+	rows := map[string][][]interface{}{
+		"a": {
+			{"", "x", 10.0, nil, ""},
+			{"", "y", 0.0, nil, ""},
+			{"", "only_a", 5.0, nil, ""},
+			{"", "z", 1.0, nil, "ms"},
+			{"", "z", 2.0, nil, "s"},
+		},
+		"b": {
+			{"", "x", 0.0, int64p(15), ""},
+			{"", "y", 4.0, nil, ""},
+			{"", "only_b", 2.0, nil, ""},
+			{"", "z", 3.0, nil, "ms"},
+		},
+	}
+	session := newFakeSession()
+	session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+		return rows[q.vals[0].(string)], nil
+	})
+	s := newTestServer(t, testConfig(), session)
+
+	resp, err := s.CompareOrigins(context.Background(), &pb.CompareOriginsRequest{OriginA: "a", OriginB: "b"})
+	if err != nil {
+		t.Fatalf("CompareOrigins() = %v", err)
+	}
+	want := []*pb.EventComparison{
+		{Name: "only_a", ValueA: float64p(5), Difference: -5},
+		{Name: "only_b", ValueB: float64p(2), Difference: 2},
+		{Name: "x", ValueA: float64p(10), ValueB: float64p(15), Difference: 5, Ratio: float64p(1.5), PercentChange: float64p(50)},
+		{Name: "y", ValueA: float64p(0), ValueB: float64p(4), Difference: 4}, // no ratio of zero
+		{Name: "z", Unit: "ms", ValueA: float64p(1), ValueB: float64p(3), Difference: 2, Ratio: float64p(3), PercentChange: float64p(200)},
+		{Name: "z", Unit: "s", ValueA: float64p(2), Difference: -2},
+	}
+	if len(resp.Events) != len(want) {
+		t.Fatalf("CompareOrigins() = %v, want %v", resp.Events, want)
+	}
+	for i, c := range resp.Events {
+		if !proto.Equal(c, want[i]) {
+			t.Errorf("CompareOrigins() comparison %d = %v, want %v", i, c, want[i])
+		}
+	}
+
+	for _, req := range []*pb.CompareOriginsRequest{{OriginA: "a"}, {OriginB: "b"}} {
+		if _, err := s.CompareOrigins(context.Background(), req); errorCode(err) != twirp.InvalidArgument {
+			t.Errorf("CompareOrigins(%v) = %v, want InvalidArgument", req, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/mykodev/myko/config"
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// convertUnits converts the values of the events to the target
+// unit, merging the events of the same name. It fails if any
+// event has a unit of another dimension than the target unit.
+func convertUnits(units map[string]config.Unit, v map[string]*pb.Event, target string) (map[string]*pb.Event, error) {
+	to, ok := units[target]
+	if !ok {
+		return nil, twirp.InvalidArgumentError("convert_to_unit", fmt.Sprintf("unknown unit %q", target))
+	}
+
+	converted := make(map[string]*pb.Event, len(v))
+	for _, e := range v {
+		from, ok := units[e.Unit]
+		if !ok {
+			return nil, twirp.NewErrorf(twirp.FailedPrecondition, "event %q has unknown unit %q", e.Name, e.Unit)
+		}
+		if from.Dimension != to.Dimension {
+			return nil, twirp.NewErrorf(twirp.FailedPrecondition,
+				"can't convert event %q from %q (%s) to %q (%s)", e.Name, e.Unit, from.Dimension, target, to.Dimension)
+		}
+
+		// Converted integer values are not integers in general.
+		event := &pb.Event{
+			Name:  e.Name,
+			Unit:  target,
+			Value: totalValue(e) * from.Factor / to.Factor,
+			Count: e.Count,
+		}
+		k := groupKey(event.Name, event.Unit)
+		if c, ok := converted[k]; ok {
+			if err := addValue(c, event); err != nil {
+				return nil, err
+			}
+		} else {
+			converted[k] = event
+		}
+	}
+	return converted, nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/mykodev/myko/config"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestConvertUnits(t *testing.T) {
+	units := map[string]config.Unit{
+		"ms": {Dimension: "time", Factor: 0.001},
+		"s":  {Dimension: "time", Factor: 1},
+		"B":  {Dimension: "bytes", Factor: 1},
+		"KB": {Dimension: "bytes", Factor: 1000},
+	}
+	tests := []struct {
+		name     string
+		events   []*pb.Event
+		target   string
+		want     []*pb.Event
+		wantCode twirp.ErrorCode
+	}{
+		{
+			name:   "ms to s",
+			events: []*pb.Event{{Name: "latency", Unit: "ms", Value: 1500, Count: 3}},
+			target: "s",
+			want:   []*pb.Event{{Name: "latency", Unit: "s", Value: 1.5, Count: 3}},
+		},
+		{
+			name: "merged",
+			events: []*pb.Event{
+				{Name: "size", Unit: "KB", Value: 2, Count: 1},
+				{Name: "size", Unit: "B", IntValue: int64p(24), Count: 2},
+				{Name: "other", Unit: "B", Value: 1, Count: 1},
+			},
+			target: "B",
+			want: []*pb.Event{
+				{Name: "other", Unit: "B", Value: 1, Count: 1},
+				{Name: "size", Unit: "B", Value: 2024, Count: 3},
+			},
+		},
+		{
+			name:     "other dimension",
+			events:   []*pb.Event{{Name: "latency", Unit: "ms", Value: 1}},
+			target:   "B",
+			wantCode: twirp.FailedPrecondition,
+		},
+		{
+			name:     "unknown unit",
+			events:   []*pb.Event{{Name: "latency", Unit: "min", Value: 1}},
+			target:   "s",
+			wantCode: twirp.FailedPrecondition,
+		},
+		{
+			name:     "unknown target",
+			events:   []*pb.Event{{Name: "latency", Unit: "ms", Value: 1}},
+			target:   "h",
+			wantCode: twirp.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := make(map[string]*pb.Event)
+			for _, e := range tt.events {
+				v[groupKey(e.Name, e.Unit)] = e
+			}
+			got, err := convertUnits(units, v, tt.target)
+			if errorCode(err) != tt.wantCode || (tt.wantCode == "" && err != nil) {
+				t.Fatalf("convertUnits() = %v, want code %q", err, tt.wantCode)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("convertUnits() = %v, want %v", got, tt.want)
+			}
+			for _, want := range tt.want {
+				if e := got[groupKey(want.Name, want.Unit)]; !proto.Equal(e, want) {
+					t.Errorf("convertUnits() %s = %v, want %v", want.Name, e, want)
+				}
+			}
+		})
+	}
+}
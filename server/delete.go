@@ -0,0 +1,153 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/mykodev/myko/datastore"
+)
+
+const (
+	deleteImmediate = "immediate"
+	deleteBatch     = "batch"
+	deleteExpire    = "expire"
+)
+
+const (
+	deleteStmt       = `DELETE FROM {{.Keyspace}}.events WHERE id = ?`
+	expireSelectStmt = `
+		SELECT trace_id, origin, attr_key, attr_value, event, value, int_value, unit, created_at, TTL(created_at)
+		FROM {{.Keyspace}}.events WHERE id = ?`
+	expireInsertStmt = `
+		INSERT INTO {{.Keyspace}}.events
+		(id, trace_id, origin, attr_key, attr_value, event, value, int_value, unit, created_at)
+		VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )
+		USING TTL ?`
+)
+
+// deleteRows removes the rows with the configured strategy.
+func (s *Server) deleteRows(ids []gocql.UUID) error {
+	s.forgetRows(ids)
+	switch s.deletes.Strategy {
+	case deleteBatch:
+		return s.deleteBatches(ids)
+	case deleteExpire:
+		return s.expireRows(ids)
+	}
+	for _, id := range ids {
+		log.Printf("Deleting %q", id)
+
+		q, err := s.session.Query(deleteStmt, id)
+		if err != nil {
+			return err
+		}
+		if err := q.Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) deleteBatches(ids []gocql.UUID) error {
+	n := s.deletes.BatchSize
+	if n < 1 {
+		n = 1
+	}
+	for len(ids) > 0 {
+		chunk := ids
+		if len(chunk) > n {
+			chunk = chunk[:n]
+		}
+		ids = ids[len(chunk):]
+
+		batch := s.session.NewBatch(gocql.UnloggedBatch)
+		for _, id := range chunk {
+			if err := batch.Query(deleteStmt, id); err != nil {
+				return err
+			}
+		}
+		log.Printf("Deleting %d records", len(chunk))
+		if err := s.session.ExecuteBatch(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expireRows re-writes each row with the expire TTL, so it
+// expires instead of being deleted. Rows already gone or
+// expiring sooner than the expire TTL are skipped.
+func (s *Server) expireRows(ids []gocql.UUID) error {
+	for _, id := range ids {
+		stmt, vals, ok, err := s.expireQuery(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		log.Printf("Expiring %q in %ds", id, vals[len(vals)-1])
+		q, err := s.session.Query(stmt, vals...)
+		if err != nil {
+			return err
+		}
+		if err := q.Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expireQuery returns the statement re-writing the row with the
+// expire TTL, false if the row is gone or expires sooner anyway.
+func (s *Server) expireQuery(id gocql.UUID) (string, []interface{}, bool, error) {
+	ttl := int64(s.deletes.ExpireTTL / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+	q, err := s.session.Query(expireSelectStmt, id)
+	if err != nil {
+		return "", nil, false, err
+	}
+	var (
+		traceID, origin, attrKey, attrValue, name, unit string
+		value                                           float64
+		intValue                                        *int64
+		createdAt                                       time.Time
+		remaining                                       *int64 // nil if the row doesn't expire
+	)
+	if err := q.Scan(&traceID, &origin, &attrKey, &attrValue, &name, &value, &intValue, &unit, &createdAt, &remaining); err != nil {
+		if err == gocql.ErrNotFound {
+			return "", nil, false, nil
+		}
+		return "", nil, false, err
+	}
+	if remaining != nil && *remaining <= ttl {
+		return "", nil, false, nil // re-writing would extend its life
+	}
+	return expireInsertStmt,
+		[]interface{}{id, traceID, origin, attrKey, attrValue, name, value, intValue, unit, createdAt, ttl},
+		true, nil
+}
+
+// removeQuery adds the statement removing the row with the
+// configured strategy to the batch.
+func (s *Server) removeQuery(batch datastore.Batch, id gocql.UUID) error {
+	if s.deletes.Strategy != deleteExpire {
+		return batch.Query(deleteStmt, id)
+	}
+	stmt, vals, ok, err := s.expireQuery(id)
+	if err != nil || !ok {
+		return err
+	}
+	return batch.Query(stmt, vals...)
+}
+
+// forgetRows stops merging flushed events into the rows.
+// It needs to be called before they are removed.
+func (s *Server) forgetRows(ids []gocql.UUID) {
+	if m := s.batchWriter.merger; m != nil {
+		m.forget(ids)
+	}
+}
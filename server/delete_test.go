@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/mykodev/myko/config"
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestExpireRows(t *testing.T) {
+	tests := []struct {
+		name      string
+		found     bool
+		remaining *int64
+		wantTTL   int64 // zero if the row is not re-written
+	}{
+		{name: "no TTL", found: true, wantTTL: 60},
+		{name: "expires later", found: true, remaining: int64p(3600), wantTTL: 60},
+		{name: "expires sooner", found: true, remaining: int64p(30)},
+		{name: "gone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if !strings.Contains(q.stmt, "SELECT") || !tt.found {
+					return nil, nil
+				}
+				return [][]interface{}{{
+					"trace", "origin", "", "", "event", 1.0, nil, "ms", time.Now(), tt.remaining,
+				}}, nil
+			})
+			cfg := testConfig()
+			cfg.DeleteConfig.Strategy = deleteExpire
+			cfg.DeleteConfig.ExpireTTL = time.Minute
+			s := newTestServer(t, cfg, session)
+
+			if err := s.deleteRows([]gocql.UUID{gocql.MustRandomUUID()}); err != nil {
+				t.Fatalf("deleteRows() = %v", err)
+			}
+			inserts := session.ran("INSERT")
+			if tt.wantTTL == 0 {
+				if len(inserts) > 0 {
+					t.Errorf("deleteRows() re-wrote the row")
+				}
+				return
+			}
+			if len(inserts) != 1 {
+				t.Fatalf("deleteRows() re-wrote %d rows, want 1", len(inserts))
+			}
+			vals := inserts[0].vals
+			if ttl := vals[len(vals)-1].(int64); ttl != tt.wantTTL {
+				t.Errorf("re-written with TTL %d, want %d", ttl, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestReplaceEvents(t *testing.T) {
+	id := gocql.MustRandomUUID()
+	entries := []*pb.Entry{{Origin: "a", Events: []*pb.Event{{Name: "e", Unit: "ms", Value: 1}}}}
+	tests := []struct {
+		name        string
+		cfg         func(c *config.Config)
+		paused      bool
+		entries     []*pb.Entry
+		wantCode    twirp.ErrorCode
+		wantDeletes int
+		wantInserts int // including the expiring re-writes
+	}{
+		{name: "replaced", entries: entries, wantDeletes: 1, wantInserts: 1},
+		{name: "paused", paused: true, entries: entries, wantCode: twirp.Unavailable},
+		{
+			name: "catalog",
+			cfg: func(c *config.Config) {
+				c.IngestConfig.CatalogMode = catalogStrict
+			},
+			entries:  entries,
+			wantCode: twirp.InvalidArgument,
+		},
+		{
+			name: "expire",
+			cfg: func(c *config.Config) {
+				c.DeleteConfig.Strategy = deleteExpire
+			},
+			entries:     entries,
+			wantInserts: 2,
+		},
+		{
+			name: "too large",
+			cfg: func(c *config.Config) {
+				c.DeleteConfig.MaxReplaceRows = 1
+			},
+			entries:  entries,
+			wantCode: twirp.FailedPrecondition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if strings.Contains(q.stmt, "WHERE id = ?") {
+					return [][]interface{}{{
+						"", "a", "", "", "e", 2.0, nil, "ms", time.Now(), int64p(3600),
+					}}, nil
+				}
+				return idRows(id), nil
+			})
+			cfg := testConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			s := newTestServer(t, cfg, session)
+			s.batchWriter.paused = tt.paused
+
+			_, err := s.ReplaceEvents(context.Background(), &pb.ReplaceEventsRequest{
+				Delete:  &pb.DeleteEventsRequest{Origin: "a"},
+				Entries: tt.entries,
+			})
+			if code := errorCode(err); code != tt.wantCode || (code == "" && err != nil) {
+				t.Fatalf("ReplaceEvents() = %v, want code %q", err, tt.wantCode)
+			}
+			if n := len(session.executed("DELETE")); n != tt.wantDeletes {
+				t.Errorf("ReplaceEvents() deleted %d rows, want %d", n, tt.wantDeletes)
+			}
+			if n := len(session.executed("INSERT")); n != tt.wantInserts {
+				t.Errorf("ReplaceEvents() wrote %d rows, want %d", n, tt.wantInserts)
+			}
+		})
+	}
+}
+
+func TestDeleteEventsByIDs(t *testing.T) {
+	a, b := gocql.MustRandomUUID(), gocql.MustRandomUUID()
+	tests := []struct {
+		name     string
+		req      *pb.DeleteEventsRequest
+		wantCode twirp.ErrorCode
+		want     []gocql.UUID // deleted
+	}{
+		{name: "ids", req: &pb.DeleteEventsRequest{Ids: []string{a.String(), b.String()}}, want: []gocql.UUID{a, b}},
+		{name: "too many", req: &pb.DeleteEventsRequest{Ids: []string{a.String(), b.String(), a.String()}}, wantCode: twirp.InvalidArgument},
+		{name: "invalid", req: &pb.DeleteEventsRequest{Ids: []string{"a"}}, wantCode: twirp.InvalidArgument},
+		{name: "with filter", req: &pb.DeleteEventsRequest{Origin: "o", Ids: []string{a.String()}}, wantCode: twirp.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			cfg := testConfig()
+			cfg.DeleteConfig.MaxIDs = 2
+			s := newTestServer(t, cfg, session)
+
+			resp, err := s.DeleteEvents(context.Background(), tt.req)
+			if code := errorCode(err); code != tt.wantCode || (code == "" && err != nil) {
+				t.Fatalf("DeleteEvents() = %v, want code %q", err, tt.wantCode)
+			}
+			var deleted []gocql.UUID
+			for _, q := range session.ran("DELETE") {
+				deleted = append(deleted, q.vals[0].(gocql.UUID))
+			}
+			if !reflect.DeepEqual(deleted, tt.want) {
+				t.Errorf("DeleteEvents() deleted %v, want %v", deleted, tt.want)
+			}
+			if len(session.ran("SELECT")) > 0 {
+				t.Error("DeleteEvents() scanned for the ids")
+			}
+			if err == nil && resp.Deleted != int64(len(tt.want)) {
+				t.Errorf("DeleteEvents() deleted = %d, want %d", resp.Deleted, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestDeleteEventsMaxScan(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxScan     int
+		matching    int
+		wantLimit   string
+		wantCode    twirp.ErrorCode
+		wantDeleted int64
+	}{
+		{name: "within budget", maxScan: 3, matching: 2, wantLimit: "LIMIT 4", wantDeleted: 2},
+		{name: "at budget", maxScan: 3, matching: 3, wantLimit: "LIMIT 4", wantDeleted: 3},
+		{name: "past budget", maxScan: 3, matching: 10, wantLimit: "LIMIT 4", wantCode: twirp.FailedPrecondition},
+		{name: "unlimited", matching: 10, wantDeleted: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if !strings.HasPrefix(q.stmt, "SELECT id") {
+					return nil, nil
+				}
+				n := tt.matching
+				if tt.maxScan > 0 && n > tt.maxScan+1 {
+					n = tt.maxScan + 1 // as limited by the statement
+				}
+				ids := make([]gocql.UUID, n)
+				for i := range ids {
+					ids[i] = gocql.MustRandomUUID()
+				}
+				return idRows(ids...), nil
+			})
+			cfg := testConfig()
+			cfg.DeleteConfig.MaxScan = tt.maxScan
+			s := newTestServer(t, cfg, session)
+
+			resp, err := s.DeleteEvents(context.Background(), &pb.DeleteEventsRequest{Origin: "o"})
+			if errorCode(err) != tt.wantCode || (tt.wantCode == "" && err != nil) {
+				t.Fatalf("DeleteEvents() = %v, want code %q", err, tt.wantCode)
+			}
+			selects := session.ran("SELECT id")
+			if len(selects) != 1 {
+				t.Fatalf("DeleteEvents() selected ids %d times, want once", len(selects))
+			}
+			if hasLimit := strings.Contains(selects[0].stmt, "LIMIT"); hasLimit != (tt.wantLimit != "") ||
+				!strings.Contains(selects[0].stmt, tt.wantLimit) {
+				t.Errorf("DeleteEvents() selected ids with %q, want %q", selects[0].stmt, tt.wantLimit)
+			}
+			deletes := session.ran("DELETE")
+			if err != nil {
+				if len(deletes) > 0 {
+					t.Errorf("DeleteEvents() deleted %d rows before refusing", len(deletes))
+				}
+				return
+			}
+			if resp.Deleted != tt.wantDeleted || int64(len(deletes)) != tt.wantDeleted {
+				t.Errorf("DeleteEvents() deleted %d rows with %d statements, want %d", resp.Deleted, len(deletes), tt.wantDeleted)
+			}
+		})
+	}
+}
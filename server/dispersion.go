@@ -0,0 +1,72 @@
+package server
+
+import "sync"
+
+// dispersion computes the variance of the row values per group
+// with Welford's online algorithm, which stays numerically stable
+// for large counts. It is safe for concurrent use.
+type dispersion struct {
+	mu     sync.Mutex
+	groups map[string]*welford
+}
+
+type welford struct {
+	n    int64
+	mean float64
+	m2   float64
+}
+
+func newDispersion() *dispersion {
+	return &dispersion{groups: make(map[string]*welford)}
+}
+
+func (d *dispersion) add(key string, v float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w, ok := d.groups[key]
+	if !ok {
+		w = &welford{}
+		d.groups[key] = w
+	}
+	w.n++
+	delta := v - w.mean
+	w.mean += delta / float64(w.n)
+	w.m2 += delta * (v - w.mean)
+}
+
+// merge adds the values collected by o, combining the
+// partial aggregates with Chan et al.'s parallel algorithm.
+func (d *dispersion) merge(o *dispersion) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for key, b := range o.groups {
+		a, ok := d.groups[key]
+		if !ok {
+			w := *b
+			d.groups[key] = &w
+			continue
+		}
+		n := a.n + b.n
+		delta := b.mean - a.mean
+		a.m2 += b.m2 + delta*delta*float64(a.n)*float64(b.n)/float64(n)
+		a.mean += delta * float64(b.n) / float64(n)
+		a.n = n
+	}
+}
+
+// variance returns the population variance of the values
+// of the group, false if the group has no values.
+func (d *dispersion) variance(key string) (float64, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w, ok := d.groups[key]
+	if !ok || w.n == 0 {
+		return 0, false
+	}
+	return w.m2 / float64(w.n), true
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// twoPassVariance returns the population variance of the values,
+// computing the mean first.
+func twoPassVariance(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var m2 float64
+	for _, v := range values {
+		m2 += (v - mean) * (v - mean)
+	}
+	return m2 / float64(len(values))
+}
+
+// closeTo reports whether got is within a relative error of want.
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-9*math.Max(1, math.Abs(want))
+}
+
+func TestDispersion(t *testing.T) {
+	large := make([]float64, 100000)
+	for i := range large {
+		large[i] = 1e9 + float64(i%7) + 0.25*float64(i%3)
+	}
+	tests := []struct {
+		name   string
+		values []float64
+	}{
+		{name: "single", values: []float64{3}},
+		{name: "small", values: []float64{2, 4, 4, 4, 5, 5, 7, 9}},
+		{name: "large offset", values: []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16}},
+		{name: "large count", values: large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := twoPassVariance(tt.values)
+
+			d := newDispersion()
+			for _, v := range tt.values {
+				d.add("k", v)
+			}
+			if got, ok := d.variance("k"); !ok || !closeTo(got, want) {
+				t.Errorf("variance() = %v, %v, want %v", got, ok, want)
+			}
+
+			// Partial aggregates of uneven chunks, as collected
+			// by the scans of token ranges.
+			merged := newDispersion()
+			for i, n := 0, 1; i < len(tt.values); i, n = i+n, n+2 {
+				end := i + n
+				if end > len(tt.values) {
+					end = len(tt.values)
+				}
+				part := newDispersion()
+				for _, v := range tt.values[i:end] {
+					part.add("k", v)
+				}
+				merged.merge(part)
+			}
+			if got, ok := merged.variance("k"); !ok || !closeTo(got, want) {
+				t.Errorf("variance() after merging = %v, %v, want %v", got, ok, want)
+			}
+		})
+	}
+
+	if _, ok := newDispersion().variance("k"); ok {
+		t.Error("variance() of no values = true, want false")
+	}
+}
+
+func TestQueryVariance(t *testing.T) {
+	rows := [][]interface{}{
+		{"", "e", 1e9 + 4, nil, ""},
+		{"", "e", 1e9 + 7, nil, ""},
+		{"", "e", 0.0, int64p(1e9 + 13), ""},
+		{"", "e", 1e9 + 16, nil, ""},
+		{"", "f", 3.0, nil, ""},
+	}
+	session := newFakeSession()
+	var (
+		mu   sync.Mutex
+		next int
+	)
+	session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+		// Each token range scans one of the rows.
+		mu.Lock()
+		defer mu.Unlock()
+		if next == len(rows) {
+			return nil, nil
+		}
+		next++
+		return rows[next-1 : next], nil
+	})
+	cfg := testConfig()
+	cfg.QueryConfig.Parallelism = 8
+	s := newTestServer(t, cfg, session)
+
+	resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o", WithVariance: true})
+	if err != nil {
+		t.Fatalf("Query() = %v", err)
+	}
+	if n := len(session.ran("SELECT")); n != cfg.QueryConfig.Parallelism {
+		t.Errorf("Query() ran %d scans, want %d", n, cfg.QueryConfig.Parallelism)
+	}
+	want := map[string]float64{"e": 22.5, "f": 0}
+	if len(resp.Events) != len(want) {
+		t.Fatalf("Query() events = %v, want %d", resp.Events, len(want))
+	}
+	for _, e := range resp.Events {
+		if e.Variance == nil || !closeTo(*e.Variance, want[e.Name]) {
+			t.Errorf("Query() variance of %q = %v, want %v", e.Name, e.Variance, want[e.Name])
+		}
+		if e.StdDev == nil || !closeTo(*e.StdDev, math.Sqrt(want[e.Name])) {
+			t.Errorf("Query() std_dev of %q = %v, want %v", e.Name, e.StdDev, math.Sqrt(want[e.Name]))
+		}
+	}
+}
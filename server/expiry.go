@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// expiryNotifier periodically checks the origins written
+// since the server started and notifies when all events
+// of an origin are expired.
+type expiryNotifier struct {
+	server  *Server
+	ttl     time.Duration
+	webhook string
+	client  *http.Client
+
+	mu      sync.Mutex
+	origins map[string]*originState
+}
+
+type originState struct {
+	lastSeen time.Time
+	notified bool
+}
+
+func newExpiryNotifier(server *Server, ttl time.Duration, webhook string) *expiryNotifier {
+	return &expiryNotifier{
+		server:  server,
+		ttl:     ttl,
+		webhook: webhook,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		origins: make(map[string]*originState),
+	}
+}
+
+// Seen records that events are written for the origin.
+func (n *expiryNotifier) Seen(origin string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.origins[origin] = &originState{lastSeen: time.Now()}
+}
+
+func (n *expiryNotifier) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		n.check()
+	}
+}
+
+func (n *expiryNotifier) check() {
+	var candidates []string
+	n.mu.Lock()
+	for origin, state := range n.origins {
+		// The events of an origin can't be expired
+		// before a TTL passes after its last write.
+		if !state.notified && time.Since(state.lastSeen) >= n.ttl {
+			candidates = append(candidates, origin)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, origin := range candidates {
+		ok, err := n.server.hasEvents(origin)
+		if err != nil {
+			log.Printf("Failed to check expiry of origin %q: %v", origin, err)
+			continue
+		}
+		if ok {
+			continue
+		}
+
+		n.mu.Lock()
+		state := n.origins[origin]
+		expired := time.Since(state.lastSeen) >= n.ttl
+		if expired {
+			state.notified = true
+		}
+		n.mu.Unlock()
+		if expired {
+			n.notify(origin)
+		}
+	}
+}
+
+func (n *expiryNotifier) notify(origin string) {
+	log.Printf("All events of origin %q are expired", origin)
+	n.server.metrics.expiredOrigins.Add(1)
+	if n.webhook == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]string{"origin": origin})
+	if err != nil {
+		log.Printf("Failed to encode expiry notification: %v", err)
+		return
+	}
+	resp, err := n.client.Post(n.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to send expiry notification: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+func TestExpiryNotifier(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		notified []string // by the webhook
+		empty    = map[string]bool{"b": true}
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Origin string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding notification: %v", err)
+		}
+		mu.Lock()
+		notified = append(notified, body.Origin)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	session := newFakeSession()
+	session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if empty[q.vals[0].(string)] {
+			return nil, nil
+		}
+		return idRows(gocql.MustRandomUUID()), nil
+	})
+	s := newTestServer(t, testConfig(), session)
+	const ttl = time.Millisecond
+	n := newExpiryNotifier(s, ttl, hook.URL)
+
+	check := func(wantExpired int64, wantNotified ...string) {
+		t.Helper()
+		time.Sleep(ttl)
+		n.check()
+		if got := s.metrics.expiredOrigins.Value(); got != wantExpired {
+			t.Errorf("expired_origins = %d, want %d", got, wantExpired)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !reflect.DeepEqual(notified, wantNotified) {
+			t.Errorf("notified %v, want %v", notified, wantNotified)
+		}
+	}
+
+	n.Seen("a")
+	n.Seen("b")
+	check(1, "b")
+	check(1, "b") // notified once
+
+	n.Seen("b") // re-armed by a write
+	check(2, "b", "b")
+
+	mu.Lock()
+	empty["a"] = true
+	mu.Unlock()
+	check(3, "b", "b", "a")
+}
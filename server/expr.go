@@ -0,0 +1,199 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// maxExprLen is the uppermost length of a value expression.
+const maxExprLen = 256
+
+// valueExpr is a compiled value expression. Its result may be
+// NaN or infinite, e.g. log(0), Query leaves these events out
+// and counts them in non_finite_values.
+type valueExpr func(value float64) float64
+
+// exprFuncs are the functions allowed in value expressions.
+var exprFuncs = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"log":   math.Log,
+	"log2":  math.Log2,
+	"log10": math.Log10,
+	"exp":   math.Exp,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+	"round": math.Round,
+}
+
+// parseValueExpr compiles an arithmetic expression over the
+// identifier "value", e.g. "value * 8" or "log10(value + 1)".
+// Only numbers, +, -, *, /, parentheses and exprFuncs are
+// allowed, evaluating it can't have any side effects.
+func parseValueExpr(s string) (valueExpr, error) {
+	if len(s) > maxExprLen {
+		return nil, fmt.Errorf("expression is longer than %d characters", maxExprLen)
+	}
+	p := &exprParser{s: s}
+	e, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at %d", p.s[p.pos], p.pos)
+	}
+	return e, nil
+}
+
+type exprParser struct {
+	s   string
+	pos int
+}
+
+// maxExprDepth bounds the nesting of parentheses and unary
+// operators, so parsing can't exhaust the stack.
+const maxExprDepth = 32
+
+// expr parses a sum of terms.
+func (p *exprParser) expr(depth int) (valueExpr, error) {
+	if depth > maxExprDepth {
+		return nil, fmt.Errorf("expression is nested deeper than %d", maxExprDepth)
+	}
+	left, err := p.term(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.term(depth)
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '+' {
+			left = func(v float64) float64 { return l(v) + right(v) }
+		} else {
+			left = func(v float64) float64 { return l(v) - right(v) }
+		}
+	}
+}
+
+// term parses a product of factors.
+func (p *exprParser) term(depth int) (valueExpr, error) {
+	left, err := p.factor(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.factor(depth)
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '*' {
+			left = func(v float64) float64 { return l(v) * right(v) }
+		} else {
+			left = func(v float64) float64 { return l(v) / right(v) }
+		}
+	}
+}
+
+// factor parses a number, the value, a function call,
+// a parenthesized expression or a negated factor.
+func (p *exprParser) factor(depth int) (valueExpr, error) {
+	switch c := p.peek(); {
+	case c == '-':
+		p.pos++
+		if depth+1 > maxExprDepth {
+			return nil, fmt.Errorf("expression is nested deeper than %d", maxExprDepth)
+		}
+		f, err := p.factor(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return func(v float64) float64 { return -f(v) }, nil
+	case c == '(':
+		p.pos++
+		e, err := p.expr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		n, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.s[start:p.pos])
+		}
+		return func(float64) float64 { return n }, nil
+	case c >= 'a' && c <= 'z':
+		start := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] >= 'a' && p.s[p.pos] <= 'z' || p.s[p.pos] >= '0' && p.s[p.pos] <= '9') {
+			p.pos++
+		}
+		name := p.s[start:p.pos]
+		if name == "value" {
+			return func(v float64) float64 { return v }, nil
+		}
+		fn, ok := exprFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown identifier %q", name)
+		}
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		arg, err := p.expr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return func(v float64) float64 { return fn(arg(v)) }, nil
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q at %d", c, p.pos)
+	}
+}
+
+// peek skips white space and returns the next
+// character, zero at the end of the expression.
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *exprParser) expect(c byte) error {
+	if p.peek() != c {
+		return fmt.Errorf("expected %q at %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
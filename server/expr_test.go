@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestParseValueExpr(t *testing.T) {
+	tests := []struct {
+		expr    string
+		value   float64
+		want    float64
+		wantErr bool
+	}{
+		{expr: "value", value: 3, want: 3},
+		{expr: "value * 8", value: 2, want: 16},
+		{expr: "1 + value * 2", value: 3, want: 7},
+		{expr: "(1 + value) * 2", value: 3, want: 8},
+		{expr: "10 - 4 - 3", want: 3},
+		{expr: "8 / 2 / 2", want: 2},
+		{expr: "--value", value: 5, want: 5},
+		{expr: "log10(value + 1)", value: 99, want: 2},
+		{expr: " abs( value ) ", value: -1.5, want: 1.5},
+		{expr: "sqrt(floor(value))", value: 9.9, want: 3},
+		{expr: "", wantErr: true},
+		{expr: "value +", wantErr: true},
+		{expr: "(value", wantErr: true},
+		{expr: "value)", wantErr: true},
+		{expr: "1..2", wantErr: true},
+		{expr: "pow(value)", wantErr: true},
+		{expr: "abs value", wantErr: true},
+		{expr: "value % 2", wantErr: true},
+		{expr: strings.Repeat("(", maxExprDepth+1) + "value" + strings.Repeat(")", maxExprDepth+1), wantErr: true},
+		{expr: strings.Repeat("-", maxExprDepth+1) + "value", wantErr: true},
+		{expr: strings.Repeat("1+", maxExprLen/2) + "1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := parseValueExpr(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseValueExpr() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := e(tt.value); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("parseValueExpr()(%g) = %g, want %g", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryNonFiniteValues(t *testing.T) {
+	tests := []struct {
+		expr          string
+		wantEvents    []string
+		wantNonFinite int64
+	}{
+		{expr: "value + 1", wantEvents: []string{"neg", "pos", "zero"}},
+		{expr: "log(value)", wantEvents: []string{"pos"}, wantNonFinite: 2}, // NaN and -Inf
+		{expr: "1 / value", wantEvents: []string{"neg", "pos"}, wantNonFinite: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				return [][]interface{}{
+					{"", "neg", -1.0, nil, ""},
+					{"", "zero", 0.0, nil, ""},
+					{"", "pos", 1.0, nil, ""},
+				}, nil
+			})
+			s := newTestServer(t, testConfig(), session)
+
+			resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "a", ValueExpression: tt.expr})
+			if err != nil {
+				t.Fatalf("Query() = %v", err)
+			}
+			var names []string
+			for _, e := range resp.Events {
+				names = append(names, e.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantEvents, ",") {
+				t.Errorf("Query() events = %v, want %v", names, tt.wantEvents)
+			}
+			if resp.NonFiniteValues != tt.wantNonFinite {
+				t.Errorf("Query() non_finite_values = %d, want %d", resp.NonFiniteValues, tt.wantNonFinite)
+			}
+		})
+	}
+}
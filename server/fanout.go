@@ -0,0 +1,97 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/mykodev/myko/datastore/cassandra"
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// fanOut is a scan across multiple keyspaces. It collects the
+// keyspaces that failed, so partial results can be flagged.
+type fanOut struct {
+	keyspaces []string
+
+	mu     sync.Mutex
+	failed map[string]bool
+}
+
+func (f *fanOut) fail(keyspace string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failed == nil {
+		f.failed = make(map[string]bool)
+	}
+	f.failed[keyspace] = true
+}
+
+// Failed returns the sorted keyspaces that failed in any
+// of the scans of the fan-out.
+func (f *fanOut) Failed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var failed []string
+	for ks := range f.failed {
+		failed = append(failed, ks)
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// aggregateKeyspaces aggregates the events matching the filter
+// in each of the fan-out keyspaces and merges them. Keyspaces
+// that fail are skipped and recorded, it only fails if all of
+// them do.
+func (s *Server) aggregateKeyspaces(opts scanOptions, filter cassandra.Filter) (map[string]*pb.Event, error) {
+	f := opts.fanOut
+	n := s.fanOutParallelism
+	if n < 1 {
+		n = 1
+	}
+
+	results := make([]map[string]*pb.Event, len(f.keyspaces))
+	slots := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, ks := range f.keyspaces {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, ks string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			ksOpts := opts
+			ksOpts.keyspace = ks
+			ksOpts.fanOut = nil
+			v, err := s.aggregate(ksOpts, filter)
+			if err != nil {
+				log.Printf("Failed to query keyspace %q: %v", ks, err)
+				f.fail(ks)
+				return
+			}
+			results[i] = v
+		}(i, ks)
+	}
+	wg.Wait()
+
+	v := make(map[string]*pb.Event)
+	var ok bool
+	for _, r := range results {
+		if r != nil {
+			ok = true
+			if err := mergeEvents(v, r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !ok {
+		return nil, twirp.NewError(twirp.Unavailable, fmt.Sprintf("all %d keyspaces failed", len(f.keyspaces)))
+	}
+	return v, nil
+}
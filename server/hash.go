@@ -0,0 +1,28 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// responseHash returns a hash of the results in the response,
+// so clients can detect whether they changed between polls.
+// The stats and the retention coverage change with each query
+// and are excluded. Events are already sorted by name and unit.
+func responseHash(resp *pb.QueryResponse) (string, error) {
+	buf, err := proto.MarshalOptions{Deterministic: true}.Marshal(&pb.QueryResponse{
+		Events:          resp.Events,
+		Histogram:       resp.Histogram,
+		FailedKeyspaces: resp.FailedKeyspaces,
+		PreviousPeriod:  resp.PreviousPeriod,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
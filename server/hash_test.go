@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestQueryHash(t *testing.T) {
+	rows := [][]interface{}{
+		{"", "a", 1.0, nil, ""},
+		{"", "b", 2.0, nil, "ms"},
+		{"", "c", 0.0, int64p(3), ""},
+		{"", "a", 4.0, nil, ""},
+	}
+	var (
+		shift int     // rotates the rows of each scan
+		extra float64 // added to the value of the first row
+	)
+	session := newFakeSession()
+	session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+		shift++
+		rotated := append(rows[shift%len(rows):], rows[:shift%len(rows)]...)
+		rotated = append([][]interface{}{{"", "d", extra, nil, ""}}, rotated...)
+		return rotated, nil
+	})
+	s := newTestServer(t, testConfig(), session)
+	hash := func() string {
+		t.Helper()
+		resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o", WithHash: true, WithStats: true})
+		if err != nil {
+			t.Fatalf("Query() = %v", err)
+		}
+		if resp.Hash == "" {
+			t.Fatal("Query() hash is empty")
+		}
+		return resp.Hash
+	}
+
+	want := hash()
+	for i := 0; i < 5; i++ {
+		if got := hash(); got != want {
+			t.Errorf("Query() hash = %q after reordering the rows, want %q", got, want)
+		}
+	}
+	extra = 1
+	if got := hash(); got == want {
+		t.Errorf("Query() hash = %q after changing a value, want it changed", got)
+	}
+}
+
+func TestResponseHashIgnoresStats(t *testing.T) {
+	events := []*pb.Event{{Name: "a", Value: 1}}
+	a, err := responseHash(&pb.QueryResponse{
+		Events: events,
+		Stats:  &pb.QueryStats{RowsScanned: 1, LatencyMs: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := responseHash(&pb.QueryResponse{
+		Events:       events,
+		Stats:        &pb.QueryStats{RowsScanned: 1, LatencyMs: 250},
+		EarliestTime: timestamppb.New(time.Now()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("responseHash() = %q and %q with different stats, want them equal", a, b)
+	}
+}
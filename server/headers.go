@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// WriteModeHeader is the request header that overrides
+// how InsertEvents writes the events of a single call.
+//
+// "sync" writes the events to the datastore before responding,
+// "buffered" is the default behavior. "async" responds before the
+// events are even validated or buffered, it has the lowest latency
+// but errors are only logged and counted by the server. Async
+// requests are rejected with an Unavailable error if ingestion is
+// paused, or a ResourceExhausted error if async_queue_size of them
+// are already waiting. Unknown values are ignored.
+const WriteModeHeader = "Myko-Write-Mode"
+
+type writeMode int
+
+const (
+	writeModeBuffered writeMode = iota
+	writeModeSync
+	writeModeAsync
+)
+
+type writeModeKey struct{}
+
+// WithHeaders wraps the handler to make the myko specific
+// request headers available to the server.
+func WithHeaders(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(WriteModeHeader); v != "" {
+			switch v {
+			case "sync":
+				r = r.WithContext(context.WithValue(r.Context(), writeModeKey{}, writeModeSync))
+			case "async":
+				r = r.WithContext(context.WithValue(r.Context(), writeModeKey{}, writeModeAsync))
+			case "buffered":
+			default:
+				log.Printf("Ignoring unknown %s header value %q", WriteModeHeader, v)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func writeModeFromContext(ctx context.Context) writeMode {
+	m, _ := ctx.Value(writeModeKey{}).(writeMode)
+	return m
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestWriteModeHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		wantWritten bool
+	}{
+		{name: "default"},
+		{name: "buffered", mode: "buffered"},
+		{name: "sync", mode: "sync", wantWritten: true},
+		{name: "unknown", mode: "later"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			s := newTestServer(t, testConfig(), session)
+			s.batchWriter.lastExport = time.Now() // the buffer isn't due
+
+			srv := httptest.NewServer(WithHeaders(pb.NewServiceServer(s, nil)))
+			defer srv.Close()
+			client := pb.NewServiceProtobufClient(srv.URL, srv.Client())
+			insert := func(ctx context.Context, name string) {
+				t.Helper()
+				_, err := client.InsertEvents(ctx, &pb.InsertEventsRequest{Entries: []*pb.Entry{{
+					Origin: "o",
+					Events: []*pb.Event{{Name: name, Value: 1}},
+				}}})
+				if err != nil {
+					t.Fatalf("InsertEvents() = %v", err)
+				}
+			}
+
+			insert(context.Background(), "other")
+			header := make(http.Header)
+			if tt.mode != "" {
+				header.Set(WriteModeHeader, tt.mode)
+			}
+			ctx, err := twirp.WithHTTPRequestHeaders(context.Background(), header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			insert(ctx, "call")
+
+			var written []string
+			for _, st := range session.executed("INSERT") {
+				written = append(written, st.vals[3].(string))
+			}
+			if tt.wantWritten && (len(written) != 1 || written[0] != "call") {
+				t.Errorf("InsertEvents() wrote %v, want only the call's event", written)
+			}
+			if !tt.wantWritten && len(written) > 0 {
+				t.Errorf("InsertEvents() wrote %v, want them buffered", written)
+			}
+			if n := len(s.batchWriter.events); n != 2-len(written) {
+				t.Errorf("%d events buffered, want %d", n, 2-len(written))
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"math"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// histogram buckets the aggregated values of the given events
+// into bins separated by bounds. Bins are lower bound inclusive,
+// the first bin starts at -Inf and the last bin ends at +Inf.
+func histogram(events []*pb.Event, bounds []float64) ([]*pb.HistogramBin, error) {
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			return nil, twirp.InvalidArgumentError("histogram_bounds", "must be in increasing order")
+		}
+	}
+
+	bins := make([]*pb.HistogramBin, len(bounds)+1)
+	lower := math.Inf(-1)
+	for i := range bins {
+		upper := math.Inf(1)
+		if i < len(bounds) {
+			upper = bounds[i]
+		}
+		bins[i] = &pb.HistogramBin{LowerBound: lower, UpperBound: upper}
+		lower = upper
+	}
+	for _, e := range events {
+		bins[binIndex(bounds, totalValue(e))].Count++
+	}
+	return bins, nil
+}
+
+// binIndex returns the index of the bin v falls into.
+func binIndex(bounds []float64, v float64) int {
+	lo, hi := 0, len(bounds)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if v < bounds[mid] {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		ints     []int64
+		bounds   []float64
+		want     []int64 // counts per bin
+		wantCode twirp.ErrorCode
+	}{
+		{name: "no bounds", values: []float64{1, 2}, want: []int64{2}},
+		{name: "lower bound inclusive", values: []float64{-1, 0, 9.9, 10, 100}, bounds: []float64{0, 10}, want: []int64{1, 2, 2}},
+		{name: "integer values", values: []float64{0.5}, ints: []int64{9, 10}, bounds: []float64{10}, want: []int64{2, 1}},
+		{name: "empty bins", values: []float64{5}, bounds: []float64{1, 2, 3}, want: []int64{0, 0, 0, 1}},
+		{name: "not increasing", bounds: []float64{1, 1}, wantCode: twirp.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*pb.Event
+			for _, v := range tt.values {
+				events = append(events, &pb.Event{Value: v})
+			}
+			for _, v := range tt.ints {
+				events = append(events, &pb.Event{IntValue: int64p(v)})
+			}
+			bins, err := histogram(events, tt.bounds)
+			if code := errorCode(err); code != tt.wantCode || (code == "" && err != nil) {
+				t.Fatalf("histogram() = %v, want code %q", err, tt.wantCode)
+			}
+			var counts []int64
+			for _, b := range bins {
+				counts = append(counts, b.Count)
+			}
+			if !reflect.DeepEqual(counts, tt.want) {
+				t.Errorf("histogram() counts = %v, want %v", counts, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryHistogram(t *testing.T) {
+	session := newFakeSession()
+	session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+		// The histogram bins the sums per key: a=3, b=12, c=7.
+		return [][]interface{}{
+			{"", "a", 1.0, nil, ""},
+			{"", "a", 2.0, nil, ""},
+			{"", "b", 12.0, nil, ""},
+			{"", "c", 0.0, int64p(7), ""},
+		}, nil
+	})
+	s := newTestServer(t, testConfig(), session)
+
+	resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o", HistogramBounds: []float64{5, 10}})
+	if err != nil {
+		t.Fatalf("Query() = %v", err)
+	}
+	want := []*pb.HistogramBin{
+		{LowerBound: negInf, UpperBound: 5, Count: 1},
+		{LowerBound: 5, UpperBound: 10, Count: 1},
+		{LowerBound: 10, UpperBound: posInf, Count: 1},
+	}
+	if len(resp.Histogram) != len(want) {
+		t.Fatalf("Query() histogram = %v, want %v", resp.Histogram, want)
+	}
+	for i, b := range resp.Histogram {
+		if b.LowerBound != want[i].LowerBound || b.UpperBound != want[i].UpperBound || b.Count != want[i].Count {
+			t.Errorf("Query() bin %d = %v, want %v", i, b, want[i])
+		}
+	}
+}
+
+var negInf, posInf = math.Inf(-1), math.Inf(1)
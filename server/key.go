@@ -0,0 +1,59 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// key encodes the grouping attributes of an event into a single
+// string. Separators and escape characters in the attributes are
+// escaped, so attributes may contain colons. Bucket is the start of
+// the time bucket of the event, or zero if events are not bucketed.
+func key(origin, traceID, name, unit string, bucket time.Time) string {
+	var b string
+	if !bucket.IsZero() {
+		b = strconv.FormatInt(bucket.UnixMilli(), 10)
+	}
+	return escapeKeyPart(origin) + ":" + escapeKeyPart(traceID) + ":" +
+		escapeKeyPart(name) + ":" + escapeKeyPart(unit) + ":" + b
+}
+
+// groupKey is the key events are aggregated by in queries.
+func groupKey(name, unit string) string {
+	return key("", "", name, unit, time.Time{})
+}
+
+func parseKey(key string) (origin, traceID, name, unit string, bucket time.Time) {
+	var (
+		parts   []string
+		part    strings.Builder
+		escaped bool
+	)
+	for _, r := range key {
+		switch {
+		case escaped:
+			part.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	parts = append(parts, part.String())
+	if parts[4] != "" {
+		ms, _ := strconv.ParseInt(parts[4], 10, 64)
+		bucket = time.UnixMilli(ms)
+	}
+	return parts[0], parts[1], parts[2], parts[3], bucket
+}
+
+var keyEscaper = strings.NewReplacer(`\`, `\\`, `:`, `\:`)
+
+func escapeKeyPart(v string) string {
+	return keyEscaper.Replace(v)
+}
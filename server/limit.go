@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/twitchtv/twirp"
+)
+
+// LimitRequests wraps the handler to serve at most max_in_flight
+// requests concurrently, sharing the limit with binary ingestion.
+// Up to max_queued requests wait for a slot, the others are
+// rejected with a ResourceExhausted error.
+func (s *Server) LimitRequests(h http.Handler) http.Handler {
+	if s.limiter == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.limiter.acquire(r.Context()); err != nil {
+			if err == errTooManyRequests {
+				twirp.WriteError(w, errTooManyRequests)
+			}
+			return
+		}
+		defer s.limiter.release()
+		h.ServeHTTP(w, r)
+	})
+}
+
+var errTooManyRequests = twirp.NewError(twirp.ResourceExhausted, "too many concurrent requests")
+
+// requestLimiter limits the number of requests served concurrently.
+type requestLimiter struct {
+	slots     chan struct{}
+	queued    int64
+	maxQueued int64
+	metrics   *metrics
+}
+
+// newRequestLimiter returns a limiter of maxInFlight requests,
+// nil if maxInFlight is zero.
+func newRequestLimiter(maxInFlight, maxQueued int, m *metrics) *requestLimiter {
+	if maxInFlight <= 0 {
+		return nil
+	}
+	return &requestLimiter{
+		slots:     make(chan struct{}, maxInFlight),
+		maxQueued: int64(maxQueued),
+		metrics:   m,
+	}
+}
+
+// acquire waits for a slot. It returns errTooManyRequests if
+// the queue is full, or the error of ctx if it is done first.
+// A nil error needs to be followed by a release.
+func (l *requestLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		if n := atomic.AddInt64(&l.queued, 1); n > l.maxQueued {
+			atomic.AddInt64(&l.queued, -1)
+			l.metrics.rejectedRequests.Add(1)
+			return errTooManyRequests
+		}
+		l.metrics.queuedRequests.Add(1)
+		select {
+		case l.slots <- struct{}{}:
+			atomic.AddInt64(&l.queued, -1)
+			l.metrics.queuedRequests.Add(-1)
+		case <-ctx.Done():
+			atomic.AddInt64(&l.queued, -1)
+			l.metrics.queuedRequests.Add(-1)
+			return ctx.Err()
+		}
+	}
+	l.metrics.inFlightRequests.Add(1)
+	return nil
+}
+
+func (l *requestLimiter) release() {
+	l.metrics.inFlightRequests.Add(-1)
+	<-l.slots
+}
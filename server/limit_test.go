@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimitRequests(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxInFlight = 1
+	cfg.MaxQueued = 1
+	s := newTestServer(t, cfg, newFakeSession())
+
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	srv := httptest.NewServer(s.LimitRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	})))
+	defer srv.Close()
+
+	get := func() (int, string) {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Errorf("GET = %v", err)
+			return 0, ""
+		}
+		defer resp.Body.Close()
+		var body struct{ Code string }
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body.Code
+	}
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+		}
+	}
+
+	statuses := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			status, _ := get()
+			statuses <- status
+		}()
+	}
+	<-started // one request is served, the other one waits
+	waitFor("a queued request", func() bool { return s.metrics.queuedRequests.Value() == 1 })
+	if got := s.metrics.inFlightRequests.Value(); got != 1 {
+		t.Errorf("in_flight_requests = %d, want 1", got)
+	}
+
+	if status, code := get(); status != http.StatusTooManyRequests || code != "resource_exhausted" {
+		t.Errorf("GET over the limit = %d %q, want %d %q", status, code, http.StatusTooManyRequests, "resource_exhausted")
+	}
+	if got := s.metrics.rejectedRequests.Value(); got != 1 {
+		t.Errorf("rejected_requests = %d, want 1", got)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if status := <-statuses; status != http.StatusOK {
+			t.Errorf("GET = %d, want %d", status, http.StatusOK)
+		}
+	}
+	waitFor("the requests to finish", func() bool { return s.metrics.inFlightRequests.Value() == 0 })
+	if got := s.metrics.queuedRequests.Value(); got != 0 {
+		t.Errorf("queued_requests = %d after serving, want 0", got)
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/mykodev/myko/datastore"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// rowMerger remembers the rows recently written by the server,
+// so the data points flushed again for the same key within the
+// window are added to the row rather than written to a new one.
+// Rows are only written by the server that created them, so the
+// remembered value is the row's value unless it was removed.
+// Merges are conditional on the row still existing, a removed
+// row is written again as a new one.
+type rowMerger struct {
+	mu     sync.Mutex // held during flushes
+	window time.Duration
+	rows   map[string]*mergedRow
+	keys   map[gocql.UUID]string // id -> key of rows
+}
+
+type mergedRow struct {
+	id        gocql.UUID
+	createdAt time.Time
+	expires   time.Time // zero if the row doesn't expire
+	written   time.Time
+	event     *pb.Event
+}
+
+func newRowMerger(window time.Duration) *rowMerger {
+	return &rowMerger{
+		window: window,
+		rows:   make(map[string]*mergedRow),
+		keys:   make(map[gocql.UUID]string),
+	}
+}
+
+// forget forgets the rows removed by the server,
+// so nothing is merged into them anymore.
+func (m *rowMerger) forget(ids []gocql.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		if k, ok := m.keys[id]; ok {
+			delete(m.rows, k)
+			delete(m.keys, id)
+		}
+	}
+}
+
+func (m *rowMerger) remember(k string, r *mergedRow) {
+	m.rows[k] = r
+	m.keys[r.id] = k
+}
+
+// flush writes the events, merging them into the rows written
+// within the window. Merges are written one by one with a
+// lightweight transaction and removed from events, the new rows
+// are written in the batch. If it fails, events holds the ones
+// not written.
+func (m *rowMerger) flush(b *batchWriter, batch datastore.Batch, events map[string]*pb.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for k, r := range m.rows {
+		if now.Sub(r.written) >= m.window {
+			delete(m.rows, k)
+			delete(m.keys, r.id)
+		}
+	}
+
+	written := make(map[string]*mergedRow, len(events))
+	for _, key := range orderKeys(events, b.order) {
+		e := proto.Clone(events[key]).(*pb.Event)
+		if r, ok := m.rows[key]; ok && addValue(e, r.event) == nil {
+			merged := &mergedRow{id: r.id, createdAt: r.createdAt, expires: r.expires, written: now, event: e}
+			applied, err := b.server.mergeQuery(merged, now)
+			if err != nil {
+				return err
+			}
+			if applied {
+				m.remember(key, merged)
+				delete(events, key)
+				continue
+			}
+			// The row is gone, write the events to a new one.
+			delete(m.rows, key)
+			delete(m.keys, r.id)
+			e = proto.Clone(events[key]).(*pb.Event)
+		} else if ok {
+			// The sum overflows, write the events to a new row.
+			delete(m.rows, key)
+			delete(m.keys, r.id)
+		}
+		id, err := gocql.RandomUUID()
+		if err != nil {
+			return err
+		}
+		r := &mergedRow{id: id, createdAt: createdAt(key), written: now, event: e}
+		if ttl := b.server.eventTTLs.ttlOf(eventOf(key)); ttl > 0 {
+			r.expires = now.Add(ttl)
+		}
+		if err := b.server.insertQuery(batch, key, e, r.id, r.createdAt); err != nil {
+			return err
+		}
+		written[key] = r
+	}
+	if err := b.execute(batch); err != nil {
+		return err
+	}
+	for k, r := range written {
+		m.remember(k, r)
+	}
+	return nil
+}
+
+const mergeStmt = `
+		UPDATE {{.Keyspace}}.events USING TTL ?
+		SET value = ?, int_value = ?
+		WHERE id = ? IF EXISTS`
+
+// mergeQuery updates the value of the row if it still exists,
+// keeping its expiry. It reports whether the row is updated.
+func (s *Server) mergeQuery(r *mergedRow, now time.Time) (bool, error) {
+	var ttl int64
+	if !r.expires.IsZero() {
+		ttl = int64(r.expires.Sub(now) / time.Second)
+		if ttl < 1 {
+			return false, nil // about to expire
+		}
+	}
+	q, err := s.session.Query(mergeStmt,
+		ttl, r.event.Value, r.event.IntValue, r.id)
+	if err != nil {
+		return false, err
+	}
+	return q.ScanCAS()
+}
+
+// eventOf returns the event name of the key.
+func eventOf(key string) string {
+	_, _, name, _, _ := parseKey(key)
+	return name
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestRowMerger(t *testing.T) {
+	tests := []struct {
+		name       string
+		exists     bool // whether the row exists at the second flush
+		delete     bool // whether the row is deleted before it
+		wantMerges int
+		wantRows   int
+	}{
+		{name: "merged", exists: true, wantMerges: 1, wantRows: 1},
+		{name: "removed elsewhere", wantMerges: 1, wantRows: 2},
+		{name: "deleted", exists: true, delete: true, wantRows: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if strings.Contains(q.stmt, "IF EXISTS") && tt.exists {
+					return [][]interface{}{{true}}, nil
+				}
+				return nil, nil
+			})
+			cfg := testConfig()
+			cfg.FlushConfig.MergeWindow = time.Minute
+			cfg.DataConfig.CassandraConfig.TTL = time.Hour
+			s := newTestServer(t, cfg, session)
+
+			write := func() {
+				t.Helper()
+				entry := &pb.Entry{Origin: "a", Events: []*pb.Event{{Name: "e", Value: 1}}}
+				if err := s.batchWriter.WriteSync([]*pb.Entry{entry}); err != nil {
+					t.Fatalf("WriteSync() = %v", err)
+				}
+			}
+			write()
+			inserts := session.executed("INSERT")
+			if len(inserts) != 1 {
+				t.Fatalf("first flush wrote %d rows, want 1", len(inserts))
+			}
+			id := inserts[0].vals[0].(string)
+			if tt.delete {
+				if _, err := s.DeleteEvents(context.Background(), &pb.DeleteEventsRequest{Ids: []string{id}}); err != nil {
+					t.Fatalf("DeleteEvents() = %v", err)
+				}
+			}
+			write()
+
+			merges := session.ran("IF EXISTS")
+			if len(merges) != tt.wantMerges {
+				t.Fatalf("second flush merged %d times, want %d", len(merges), tt.wantMerges)
+			}
+			if len(merges) > 0 {
+				vals := merges[0].vals
+				if ttl := vals[0].(int64); ttl > 3600 || ttl < 3500 {
+					t.Errorf("merge TTL = %d, want the remaining TTL of the row", ttl)
+				}
+				if v := vals[1].(float64); v != 2 {
+					t.Errorf("merged value = %v, want 2", v)
+				}
+				if got := vals[3].(gocql.UUID).String(); got != id {
+					t.Errorf("merged into %q, want %q", got, id)
+				}
+			}
+			if n := len(session.executed("INSERT")); n != tt.wantRows {
+				t.Errorf("flushes wrote %d rows, want %d", n, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import "expvar"
+
+// Registry is where the metrics of a server are registered.
+// An unpublished *expvar.Map implements it, so does one
+// created with expvar.NewMap.
+type Registry interface {
+	Set(name string, v expvar.Var)
+}
+
+// Option configures a server.
+type Option func(*Server)
+
+// WithRegistry registers the metrics of the server
+// to r instead of a registry local to the server.
+func WithRegistry(r Registry) Option {
+	return func(s *Server) {
+		s.registry = r
+	}
+}
+
+type metrics struct {
+	unitLimitViolations  *expvar.Int
+	valueLimitViolations *expvar.Int
+	expiredOrigins       *expvar.Int
+	activeConns          *expvar.Int
+	rejectedConns        *expvar.Int
+	inFlightRequests     *expvar.Int
+	queuedRequests       *expvar.Int
+	rejectedRequests     *expvar.Int
+	schemaDrifts         *expvar.Int
+	asyncInsertErrors    *expvar.Int
+	queuedAsyncInserts   *expvar.Int
+	rejectedAsyncInserts *expvar.Int
+	catalogViolations    *expvar.Int
+	sampledOutEntries    *expvar.Int
+}
+
+func newMetrics(r Registry) *metrics {
+	m := &metrics{
+		unitLimitViolations:  new(expvar.Int),
+		valueLimitViolations: new(expvar.Int),
+		expiredOrigins:       new(expvar.Int),
+		activeConns:          new(expvar.Int),
+		rejectedConns:        new(expvar.Int),
+		inFlightRequests:     new(expvar.Int),
+		queuedRequests:       new(expvar.Int),
+		rejectedRequests:     new(expvar.Int),
+		schemaDrifts:         new(expvar.Int),
+		asyncInsertErrors:    new(expvar.Int),
+		queuedAsyncInserts:   new(expvar.Int),
+		rejectedAsyncInserts: new(expvar.Int),
+		catalogViolations:    new(expvar.Int),
+		sampledOutEntries:    new(expvar.Int),
+	}
+	r.Set("unit_limit_violations", m.unitLimitViolations)
+	r.Set("value_limit_violations", m.valueLimitViolations)
+	r.Set("expired_origins", m.expiredOrigins)
+	r.Set("tcp_active_conns", m.activeConns)
+	r.Set("tcp_rejected_conns", m.rejectedConns)
+	r.Set("in_flight_requests", m.inFlightRequests)
+	r.Set("queued_requests", m.queuedRequests)
+	r.Set("rejected_requests", m.rejectedRequests)
+	r.Set("schema_drifts", m.schemaDrifts)
+	r.Set("async_insert_errors", m.asyncInsertErrors)
+	r.Set("queued_async_inserts", m.queuedAsyncInserts)
+	r.Set("rejected_async_inserts", m.rejectedAsyncInserts)
+	r.Set("catalog_violations", m.catalogViolations)
+	r.Set("sampled_out_entries", m.sampledOutEntries)
+	return m
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"expvar"
+	"math"
+	"testing"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestWithRegistry(t *testing.T) {
+	global := func() map[string]bool {
+		names := make(map[string]bool)
+		expvar.Do(func(kv expvar.KeyValue) { names[kv.Key] = true })
+		return names
+	}
+	before := global()
+
+	r, other := new(expvar.Map), new(expvar.Map)
+	s := newTestServer(t, testConfig(), newFakeSession(), WithRegistry(r))
+	newTestServer(t, testConfig(), newFakeSession(), WithRegistry(other))
+	newTestServer(t, testConfig(), newFakeSession()) // registered locally
+
+	_, err := s.InsertEvents(context.Background(), &pb.InsertEventsRequest{Entries: []*pb.Entry{{
+		Origin: "o",
+		Events: []*pb.Event{{Name: "e", Value: math.NaN()}},
+	}}})
+	if err == nil {
+		t.Fatal("InsertEvents() of NaN = nil error, want an error")
+	}
+	if v := r.Get("value_limit_violations"); v == nil || v.String() != "1" {
+		t.Errorf("value_limit_violations = %v, want 1", v)
+	}
+	if v := other.Get("value_limit_violations"); v == nil || v.String() != "0" {
+		t.Errorf("value_limit_violations of another server = %v, want 0", v)
+	}
+
+	var n int
+	r.Do(func(kv expvar.KeyValue) {
+		n++
+		if expvar.Get(kv.Key) != nil && !before[kv.Key] {
+			t.Errorf("%q is published globally", kv.Key)
+		}
+	})
+	if n == 0 {
+		t.Error("no metrics are registered")
+	}
+	if after := global(); len(after) != len(before) {
+		t.Errorf("global expvar has %d variables, want %d", len(after), len(before))
+	}
+}
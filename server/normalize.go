@@ -0,0 +1,45 @@
+package server
+
+import (
+	"strings"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// normalizeNames normalizes the names of the inserted events,
+// so the names differing only in white space, case or separators
+// are aggregated together.
+func (s *Server) normalizeNames(entries []*pb.Entry) {
+	for _, entry := range entries {
+		for _, event := range entry.Events {
+			event.Name = s.normalizeName(event.Name)
+		}
+	}
+}
+
+func (s *Server) normalizeName(name string) string {
+	n := s.normalization
+	if n.Trim {
+		name = strings.TrimSpace(name)
+	}
+	if n.Lowercase {
+		name = strings.ToLower(name)
+	}
+	if n.Separators == "" {
+		return name
+	}
+	var b strings.Builder
+	sep := false
+	for _, r := range name {
+		if strings.ContainsRune(n.Separators, r) || string(r) == n.Separator {
+			sep = true
+			continue
+		}
+		if sep && b.Len() > 0 {
+			b.WriteString(n.Separator)
+		}
+		sep = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mykodev/myko/config"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name          string
+		normalization config.NameNormalization
+		want          string
+	}{
+		{name: "http/requests", want: "http/requests"},
+		{name: " HTTP ", normalization: config.NameNormalization{Trim: true}, want: "HTTP"},
+		{name: " HTTP ", normalization: config.NameNormalization{Lowercase: true}, want: " http "},
+		{name: "http/requests", normalization: config.NameNormalization{Separators: "/-", Separator: "."}, want: "http.requests"},
+		{name: "http//-requests", normalization: config.NameNormalization{Separators: "/-", Separator: "."}, want: "http.requests"},
+		{name: "http/.requests", normalization: config.NameNormalization{Separators: "/-", Separator: "."}, want: "http.requests"},
+		{name: "/-http/requests-/", normalization: config.NameNormalization{Separators: "/-", Separator: "."}, want: "http.requests"},
+		{name: ".http.requests.", normalization: config.NameNormalization{Separators: "/", Separator: "."}, want: "http.requests"},
+		{name: "//", normalization: config.NameNormalization{Separators: "/", Separator: "."}, want: ""},
+		{name: "http_requests", normalization: config.NameNormalization{Separators: "/", Separator: "."}, want: "http_requests"},
+		{
+			name:          " HTTP/Requests ",
+			normalization: config.NameNormalization{Trim: true, Lowercase: true, Separators: "/", Separator: "."},
+			want:          "http.requests",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.IngestConfig.NameNormalization = tt.normalization
+			s := newTestServer(t, cfg, newFakeSession())
+			if got := s.normalizeName(tt.name); got != tt.want {
+				t.Errorf("normalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeNamesMerge(t *testing.T) {
+	cfg := testConfig()
+	cfg.IngestConfig.NameNormalization = config.NameNormalization{Trim: true, Lowercase: true, Separators: "/-", Separator: "."}
+	s := newTestServer(t, cfg, newFakeSession())
+	s.batchWriter.lastExport = time.Now() // nothing is due to be flushed
+
+	entry := &pb.Entry{Origin: "o", Events: []*pb.Event{
+		{Name: "HTTP/Requests", Value: 1},
+		{Name: " http-requests ", Value: 2},
+		{Name: "http..requests", Value: 3},
+		{Name: "http.requests", Value: 4, Unit: "ms"},
+	}}
+	if _, err := s.InsertEvents(context.Background(), &pb.InsertEventsRequest{Entries: []*pb.Entry{entry}}); err != nil {
+		t.Fatalf("InsertEvents() = %v", err)
+	}
+
+	got := make(map[string]float64) // of name/unit
+	for k, e := range s.batchWriter.events {
+		_, _, name, unit, _ := parseKey(k)
+		got[name+"/"+unit] += e.Value
+	}
+	want := map[string]float64{"http.requests/": 6, "http.requests/ms": 4}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buffered values by name/unit = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeEmptySeparator(t *testing.T) {
+	cfg := testConfig()
+	cfg.IngestConfig.NameNormalization = config.NameNormalization{Separators: "/"}
+	if _, err := New(cfg, WithSessions(newFakeSession(), nil)); err == nil {
+		t.Error("New() with separators but no separator succeeded")
+	}
+}
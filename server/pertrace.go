@@ -0,0 +1,53 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/mykodev/myko/datastore/cassandra"
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// traceSet is a set of trace IDs safe for concurrent use.
+type traceSet struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+func newTraceSet() *traceSet {
+	return &traceSet{ids: make(map[string]struct{})}
+}
+
+func (t *traceSet) add(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.ids[id] = struct{}{}
+}
+
+func (t *traceSet) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.ids)
+}
+
+// aggregatePerTrace aggregates the events matching the filter
+// and divides their values by the number of distinct traces.
+func (s *Server) aggregatePerTrace(opts scanOptions, filter cassandra.Filter) (map[string]*pb.Event, error) {
+	opts.traces = newTraceSet()
+	v, err := s.aggregate(opts, filter)
+	if err != nil {
+		return nil, err
+	}
+	n := opts.traces.len()
+	if n == 0 {
+		return nil, twirp.NotFoundError("no traces match the query")
+	}
+	for _, e := range v {
+		e.Value = totalValue(e) / float64(n)
+		e.IntValue = nil
+	}
+	return v, nil
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestQueryPerTrace(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     [][]interface{}
+		want     []*pb.Event
+		wantCode twirp.ErrorCode
+	}{
+		{
+			name: "divided",
+			rows: [][]interface{}{
+				{"t1", "a", 4.0, nil, ""},
+				{"t2", "a", 6.0, nil, ""},
+				{"t2", "b", 0.0, int64p(3), ""},
+				{"", "b", 0.0, int64p(1), ""}, // not part of a trace
+			},
+			want: []*pb.Event{{Name: "a", Value: 5}, {Name: "b", Value: 2}},
+		},
+		{name: "no rows", wantCode: twirp.NotFound},
+		{name: "no traces", rows: [][]interface{}{{"", "a", 1.0, nil, ""}}, wantCode: twirp.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				return tt.rows, nil
+			})
+			s := newTestServer(t, testConfig(), session)
+
+			resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o", PerTrace: true})
+			if errorCode(err) != tt.wantCode || (tt.wantCode == "" && err != nil) {
+				t.Fatalf("Query() = %v, want code %q", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if len(resp.Events) != len(tt.want) {
+				t.Fatalf("Query() events = %v, want %v", resp.Events, tt.want)
+			}
+			for i, e := range resp.Events {
+				if !proto.Equal(e, tt.want[i]) {
+					t.Errorf("Query() event %d = %v, want %v", i, e, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// Stage is a stage of the ingestion pipeline InsertEvents runs
+// the entries through before buffering them. A stage may modify
+// the entries, drop entries or events by leaving them out of the
+// returned entries, or reject the whole request with an error.
+type Stage interface {
+	Process(entries []*pb.Entry) ([]*pb.Entry, error)
+}
+
+// StageFunc adapts a function to a Stage.
+type StageFunc func(entries []*pb.Entry) ([]*pb.Entry, error)
+
+func (f StageFunc) Process(entries []*pb.Entry) ([]*pb.Entry, error) {
+	return f(entries)
+}
+
+// WithStage registers a custom ingestion stage. It runs at its
+// position in ingest.pipeline if it is listed there by name,
+// after the built-in stages otherwise.
+func WithStage(name string, stage Stage) Option {
+	return func(s *Server) {
+		s.customStages = append(s.customStages, namedStage{name: name, stage: stage})
+	}
+}
+
+type namedStage struct {
+	name  string
+	stage Stage
+}
+
+// defaultPipeline is the order of the built-in stages
+// if ingest.pipeline is not set.
+var defaultPipeline = []string{
+	"default_units",
+	"catalog",
+	"unit_limit",
+	"value_limit",
+	"sample",
+}
+
+// inPlace adapts a function modifying the entries in place.
+func inPlace(f func(entries []*pb.Entry) error) Stage {
+	return StageFunc(func(entries []*pb.Entry) ([]*pb.Entry, error) {
+		if err := f(entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	})
+}
+
+// newPipeline returns the stages in the configured order.
+func (s *Server) newPipeline(names []string) ([]Stage, error) {
+	stages := map[string]Stage{
+		"default_units": inPlace(s.applyDefaultUnits),
+		"catalog":       inPlace(s.checkCatalog),
+		"unit_limit":    inPlace(s.limitUnits),
+		"value_limit":   inPlace(s.limitValues),
+		"sample":        StageFunc(s.sample),
+	}
+	for _, c := range s.customStages {
+		if _, ok := stages[c.name]; ok {
+			return nil, fmt.Errorf("duplicate ingestion stage %q", c.name)
+		}
+		stages[c.name] = c.stage
+	}
+
+	if len(names) == 0 {
+		names = append([]string(nil), defaultPipeline...)
+		for _, c := range s.customStages {
+			names = append(names, c.name)
+		}
+	}
+	pipeline := make([]Stage, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		stage, ok := stages[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown ingestion stage %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("ingestion stage %q is listed twice", name)
+		}
+		seen[name] = true
+		pipeline = append(pipeline, stage)
+	}
+	return pipeline, nil
+}
+
+// process runs the entries through the ingestion pipeline.
+// Names are normalized before any stage runs, whatever the
+// pipeline is, since queries and deletes normalize the names
+// they filter by.
+func (s *Server) process(entries []*pb.Entry) ([]*pb.Entry, error) {
+	s.normalizeNames(entries)
+	for _, stage := range s.pipeline {
+		var err error
+		if entries, err = stage.Process(entries); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// sample keeps the sample rate of the entries. The entries
+// of a trace are kept or dropped together, by the hash of the
+// trace ID, so a trace is either complete or missing. Entries
+// without a trace ID are sampled at random.
+func (s *Server) sample(entries []*pb.Entry) ([]*pb.Entry, error) {
+	if s.sampleRate <= 0 || s.sampleRate >= 1 {
+		return entries, nil
+	}
+	kept := make([]*pb.Entry, 0, len(entries))
+	for _, e := range entries {
+		if s.sampled(e.TraceId) {
+			kept = append(kept, e)
+		} else {
+			s.metrics.sampledOutEntries.Add(1)
+		}
+	}
+	return kept, nil
+}
+
+func (s *Server) sampled(traceID string) bool {
+	if traceID == "" {
+		return rand.Float64() < s.sampleRate
+	}
+	h := fnv.New64a()
+	h.Write([]byte(traceID))
+	// FNV leaves similar IDs close, mix the bits
+	// with the finalizer of MurmurHash3.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return float64(x>>11)/(1<<53) < s.sampleRate
+}
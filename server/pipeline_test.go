@@ -0,0 +1,174 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mykodev/myko/config"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// recordStage records the event names it sees.
+type recordStage struct {
+	names []string
+}
+
+func (r *recordStage) Process(entries []*pb.Entry) ([]*pb.Entry, error) {
+	for _, e := range entries {
+		for _, event := range e.Events {
+			r.names = append(r.names, event.Name)
+		}
+	}
+	return entries, nil
+}
+
+// The traces kept and dropped at a sample rate of 0.5.
+const (
+	keptTrace    = "t1"
+	droppedTrace = "d"
+)
+
+func TestPipeline(t *testing.T) {
+	errRejected := errors.New("rejected")
+	tests := []struct {
+		name       string
+		pipeline   []string
+		entries    []*pb.Entry
+		wantSeen   []string // names seen by the "record" stage
+		wantOrigin []string // origins of the processed entries
+		wantErr    bool
+	}{
+		{
+			name:     "validate, normalize, sample",
+			pipeline: []string{"catalog", "record", "sample"},
+			entries: []*pb.Entry{
+				{Origin: "a", TraceId: keptTrace, Events: []*pb.Event{{Name: " HTTP ", Unit: "ms"}}},
+				{Origin: "b", TraceId: droppedTrace, Events: []*pb.Event{{Name: "http", Unit: "ms"}}},
+			},
+			wantSeen:   []string{"http", "http"},
+			wantOrigin: []string{"a"},
+		},
+		{
+			name:     "rejected before recording",
+			pipeline: []string{"catalog", "record"},
+			entries: []*pb.Entry{
+				{Origin: "a", Events: []*pb.Event{{Name: "http", Unit: "s"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "recorded before rejecting",
+			pipeline: []string{"record", "reject"},
+			entries: []*pb.Entry{
+				{Origin: "a", Events: []*pb.Event{{Name: "http", Unit: "s"}}},
+			},
+			wantSeen: []string{"http"},
+			wantErr:  true,
+		},
+		{
+			name:     "sampled before recording",
+			pipeline: []string{"sample", "record"},
+			entries: []*pb.Entry{
+				{Origin: "a", TraceId: keptTrace, Events: []*pb.Event{{Name: "a"}}},
+				{Origin: "b", TraceId: droppedTrace, Events: []*pb.Event{{Name: "b"}}},
+				{Origin: "c", TraceId: keptTrace, Events: []*pb.Event{{Name: "c"}}},
+			},
+			wantSeen:   []string{"a", "c"},
+			wantOrigin: []string{"a", "c"},
+		},
+		{
+			name:     "normalized without stages",
+			pipeline: []string{"record"},
+			entries: []*pb.Entry{
+				{Origin: "a", Events: []*pb.Event{{Name: "HTTP"}}},
+			},
+			wantSeen:   []string{"http"},
+			wantOrigin: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.IngestConfig.Pipeline = tt.pipeline
+			cfg.IngestConfig.SampleRate = 0.5
+			cfg.IngestConfig.CatalogMode = catalogStrict
+			cfg.IngestConfig.Catalog = map[string]config.CatalogEvent{"http": {Unit: "ms"}}
+			cfg.IngestConfig.NameNormalization = config.NameNormalization{Trim: true, Lowercase: true}
+			record := &recordStage{}
+			reject := StageFunc(func([]*pb.Entry) ([]*pb.Entry, error) {
+				return nil, errRejected
+			})
+			s := newTestServer(t, cfg, newFakeSession(), WithStage("record", record), WithStage("reject", reject))
+			if !s.sampled(keptTrace) || s.sampled(droppedTrace) {
+				t.Fatal("unexpected sampling of the test traces")
+			}
+
+			entries, err := s.process(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("process() = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(record.names, tt.wantSeen) {
+				t.Errorf("record stage saw %q, want %q", record.names, tt.wantSeen)
+			}
+			var origins []string
+			for _, e := range entries {
+				origins = append(origins, e.Origin)
+			}
+			if !reflect.DeepEqual(origins, tt.wantOrigin) {
+				t.Errorf("process() kept %q, want %q", origins, tt.wantOrigin)
+			}
+		})
+	}
+}
+
+func TestPipelineDefault(t *testing.T) {
+	record := &recordStage{}
+	s := newTestServer(t, testConfig(), newFakeSession(), WithStage("record", record))
+	if got, want := len(s.pipeline), len(defaultPipeline)+1; got != want {
+		t.Errorf("default pipeline has %d stages, want %d", got, want)
+	}
+	if _, ok := s.pipeline[len(s.pipeline)-1].(*recordStage); !ok {
+		t.Errorf("custom stage doesn't run last")
+	}
+}
+
+func TestPipelineInvalid(t *testing.T) {
+	tests := [][]string{
+		{"catalog", "unknown"},
+		{"catalog", "catalog"},
+		{"normalize"},
+	}
+	for _, pipeline := range tests {
+		t.Run(fmt.Sprint(pipeline), func(t *testing.T) {
+			cfg := testConfig()
+			cfg.IngestConfig.Pipeline = pipeline
+			if _, err := New(cfg, WithSessions(newFakeSession(), nil)); err == nil {
+				t.Errorf("New() with pipeline %q succeeded", pipeline)
+			}
+		})
+	}
+}
+
+func TestSampleTraces(t *testing.T) {
+	s := newTestServer(t, testConfig(), newFakeSession())
+	s.sampleRate = 0.25
+
+	var kept int
+	const n = 10000
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		ok := s.sampled(id)
+		if ok != s.sampled(id) {
+			t.Fatalf("trace %q is sampled inconsistently", id)
+		}
+		if ok {
+			kept++
+		}
+	}
+	if got := float64(kept) / n; got < 0.2 || got > 0.3 {
+		t.Errorf("kept %v of the traces, want about 0.25", got)
+	}
+}
@@ -0,0 +1,842 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestQueryReadYourWrites(t *testing.T) {
+	tests := []struct {
+		name           string
+		readYourWrites bool
+		wantPrimary    bool
+	}{
+		{name: "analytics"},
+		{name: "read your writes", readYourWrites: true, wantPrimary: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, analytics := newFakeSession(), newFakeSession()
+			analytics.consistency = gocql.LocalOne
+			unavailable := func(q *fakeQuery) ([][]interface{}, error) {
+				return nil, unavailableError{}
+			}
+			primary.handle(unavailable)
+			analytics.handle(unavailable)
+			cfg := testConfig()
+			cfg.QueryConfig.DowngradeConsistency = true
+			s := newTestServer(t, cfg, nil, WithSessions(primary, analytics))
+
+			s.Query(context.Background(), &pb.QueryRequest{
+				Origin:         "a",
+				LocalDc:        true,
+				ReadYourWrites: tt.readYourWrites,
+			})
+			scans, other := primary.ran("SELECT"), analytics.ran("SELECT")
+			if !tt.wantPrimary {
+				scans, other = other, scans
+			}
+			if len(scans) == 0 || len(other) > 0 {
+				t.Fatalf("Query() scanned %d times with the wanted session, %d with the other", len(scans), len(other))
+			}
+			if !tt.readYourWrites {
+				return
+			}
+			// Neither local nor downgraded scans are retried.
+			if len(scans) != 1 || scans[0].consistency != gocql.Quorum {
+				t.Errorf("Query() scanned %d times, at %v, want once at %v", len(scans), scans[0].consistency, gocql.Quorum)
+			}
+		})
+	}
+}
+
+func TestQueryKeyspaces(t *testing.T) {
+	rows := map[string][][]interface{}{
+		"myko":  {{"", "e", 1.0, nil, ""}},
+		"other": {{"", "e", 2.0, int64p(4), ""}, {"", "f", 5.0, nil, ""}},
+	}
+	tests := []struct {
+		name       string
+		keyspaces  []string
+		failing    map[string]bool
+		wantCode   twirp.ErrorCode
+		wantScans  map[string]int // per keyspace
+		wantEvents []*pb.Event
+		wantFailed []string
+	}{
+		{
+			name:       "fan-out",
+			keyspaces:  []string{"myko", "other"},
+			wantScans:  map[string]int{"myko": 1, "other": 1},
+			wantEvents: []*pb.Event{{Name: "e", Value: 3, IntValue: int64p(4)}, {Name: "f", Value: 5}},
+		},
+		{
+			name:       "partial failure",
+			keyspaces:  []string{"myko", "other"},
+			failing:    map[string]bool{"other": true},
+			wantScans:  map[string]int{"myko": 1, "other": 1},
+			wantEvents: []*pb.Event{{Name: "e", Value: 1}},
+			wantFailed: []string{"other"},
+		},
+		{
+			name:      "all failed",
+			keyspaces: []string{"myko", "other"},
+			failing:   map[string]bool{"myko": true, "other": true},
+			wantCode:  twirp.Unavailable,
+			wantScans: map[string]int{"myko": 1, "other": 1},
+		},
+		{name: "duplicate", keyspaces: []string{"other", "myko", "other"}, wantCode: twirp.InvalidArgument},
+		{name: "unknown", keyspaces: []string{"unknown"}, wantCode: twirp.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if tt.failing[q.keyspace] {
+					return nil, requestError(gocql.ErrCodeInvalid)
+				}
+				return rows[q.keyspace], nil
+			})
+			cfg := testConfig()
+			cfg.QueryConfig.FanOutKeyspaces = []string{"other"}
+			s := newTestServer(t, cfg, session)
+
+			resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "a", Keyspaces: tt.keyspaces})
+			if code := errorCode(err); code != tt.wantCode || (code == "" && err != nil) {
+				t.Fatalf("Query() = %v, want code %q", err, tt.wantCode)
+			}
+			scans := make(map[string]int)
+			for _, q := range session.ran("SELECT") {
+				scans[q.keyspace]++
+			}
+			if tt.wantScans == nil {
+				tt.wantScans = map[string]int{}
+			}
+			if !reflect.DeepEqual(scans, tt.wantScans) {
+				t.Errorf("Query() scans = %v, want %v", scans, tt.wantScans)
+			}
+			if err != nil {
+				return
+			}
+			if len(resp.Events) != len(tt.wantEvents) {
+				t.Fatalf("Query() events = %v, want %v", resp.Events, tt.wantEvents)
+			}
+			for i, e := range resp.Events {
+				if !proto.Equal(e, tt.wantEvents[i]) {
+					t.Errorf("Query() event %d = %v, want %v", i, e, tt.wantEvents[i])
+				}
+			}
+			if !reflect.DeepEqual(resp.FailedKeyspaces, tt.wantFailed) {
+				t.Errorf("Query() failed keyspaces = %v, want %v", resp.FailedKeyspaces, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestQueryEarliestTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		want  time.Duration // zero if nothing expires
+	}{
+		{name: "default", event: "requests", want: 24 * time.Hour},
+		{name: "override", event: "short", want: time.Hour},
+		{name: "old name", event: "renamed", want: 10 * time.Minute},
+		{name: "no expiry", event: "forever"},
+		{name: "all events", want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DataConfig.CassandraConfig.TTL = 24 * time.Hour
+			cfg.DataConfig.CassandraConfig.EventTTLs = map[string]time.Duration{
+				"short":   time.Hour,
+				"debug.*": 10 * time.Minute,
+				"forever": 0,
+			}
+			cfg.QueryConfig.EventAliases = map[string]string{"debug.renamed": "renamed"}
+			s := newTestServer(t, cfg, newFakeSession())
+
+			req := &pb.QueryRequest{Event: tt.event}
+			if tt.event == "" {
+				req.Origin = "a"
+			}
+			start := time.Now()
+			resp, err := s.Query(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Query() = %v", err)
+			}
+			if tt.want == 0 {
+				if resp.EarliestTime != nil {
+					t.Errorf("Query() earliest_time = %v, want none", resp.EarliestTime.AsTime())
+				}
+				return
+			}
+			if resp.EarliestTime == nil {
+				t.Fatalf("Query() earliest_time = nil, want %v ago", tt.want)
+			}
+			if ago := start.Sub(resp.EarliestTime.AsTime()); ago < tt.want-time.Second || ago > tt.want+time.Second {
+				t.Errorf("Query() earliest_time = %v ago, want %v", ago, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryParallelism(t *testing.T) {
+	// Rows with their tokens spread across the ring.
+	var rows [][]interface{}
+	var tokens []int64
+	for i := int64(0); i < 64; i++ {
+		tokens = append(tokens, math.MinInt64+i*(math.MaxInt64/32)+i)
+		name := []string{"a", "b", "c"}[i%3]
+		rows = append(rows, []interface{}{"", name, float64(i), int64p(i * 10), ""})
+	}
+	tokens[63] = math.MaxInt64
+
+	// query returns the totals and counts by event name,
+	// and the number of scans run.
+	query := func(parallelism int) (map[string][2]float64, int) {
+		t.Helper()
+		session := newFakeSession()
+		session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+			if !strings.Contains(q.stmt, "token(id)") {
+				return rows, nil
+			}
+			start, end := q.vals[len(q.vals)-2].(int64), q.vals[len(q.vals)-1].(int64)
+			var in [][]interface{}
+			for i, token := range tokens {
+				if token >= start && token <= end {
+					in = append(in, rows[i])
+				}
+			}
+			return in, nil
+		})
+		cfg := testConfig()
+		cfg.QueryConfig.Parallelism = parallelism
+		s := newTestServer(t, cfg, session)
+
+		resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o", WithCount: true})
+		if err != nil {
+			t.Fatalf("Query() = %v", err)
+		}
+		totals := make(map[string][2]float64)
+		for _, e := range resp.Events {
+			totals[e.Name] = [2]float64{totalValue(e), float64(e.Count)}
+		}
+		return totals, len(session.ran("SELECT"))
+	}
+
+	want, _ := query(1)
+	for _, parallelism := range []int{2, 4, 7} {
+		got, scans := query(parallelism)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Query() with parallelism %d = %v, want %v", parallelism, got, want)
+		}
+		if scans != parallelism {
+			t.Errorf("Query() with parallelism %d ran %d scans", parallelism, scans)
+		}
+	}
+}
+
+func TestQueryLocalDC(t *testing.T) {
+	tests := []struct {
+		name             string
+		localDC          bool
+		localUnavailable bool
+		allUnavailable   bool
+		want             []gocql.Consistency // of the scans run
+		wantErr          bool
+	}{
+		{name: "any datacenter", want: []gocql.Consistency{gocql.Quorum}},
+		{name: "local", localDC: true, want: []gocql.Consistency{gocql.LocalQuorum}},
+		{
+			name:             "escalated",
+			localDC:          true,
+			localUnavailable: true,
+			want:             []gocql.Consistency{gocql.LocalQuorum, gocql.Quorum},
+		},
+		{
+			name:             "unavailable",
+			localDC:          true,
+			localUnavailable: true,
+			allUnavailable:   true,
+			want:             []gocql.Consistency{gocql.LocalQuorum, gocql.Quorum},
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if tt.allUnavailable || (tt.localUnavailable && q.consistency == gocql.LocalQuorum) {
+					return nil, unavailableError{}
+				}
+				return [][]interface{}{{"", "a", 1.0, nil, ""}}, nil
+			})
+			s := newTestServer(t, testConfig(), session)
+
+			resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o", LocalDc: tt.localDC})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Query() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && len(resp.Events) != 1 {
+				t.Errorf("Query() returned %d events, want 1", len(resp.Events))
+			}
+			var got []gocql.Consistency
+			for _, q := range session.ran("SELECT") {
+				got = append(got, q.consistency)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query() scanned with %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryAllowFiltering(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *pb.QueryRequest
+		wantCode twirp.ErrorCode
+	}{
+		{name: "indexed", req: &pb.QueryRequest{Origin: "o"}},
+		{name: "two columns", req: &pb.QueryRequest{Origin: "o", Event: "e"}, wantCode: twirp.FailedPrecondition},
+		{name: "two columns allowed", req: &pb.QueryRequest{Origin: "o", Event: "e", AllowFiltering: true}},
+		{
+			name:     "time range",
+			req:      &pb.QueryRequest{Origin: "o", StartTime: timestamppb.New(time.Unix(10, 0))},
+			wantCode: twirp.FailedPrecondition,
+		},
+		{
+			name:     "baseline",
+			req:      &pb.QueryRequest{Origin: "o", Baseline: &pb.QueryBaseline{Origin: "b", Event: "e"}},
+			wantCode: twirp.FailedPrecondition,
+		},
+		{
+			name: "baseline allowed",
+			req:  &pb.QueryRequest{Origin: "o", Baseline: &pb.QueryBaseline{Origin: "b", Event: "e"}, AllowFiltering: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			s := newTestServer(t, testConfig(), session)
+
+			tt.req.WithStats = true
+			resp, err := s.Query(context.Background(), tt.req)
+			if errorCode(err) != tt.wantCode || (tt.wantCode == "" && err != nil) {
+				t.Fatalf("Query() = %v, want code %q", err, tt.wantCode)
+			}
+			scans := len(session.ran("SELECT"))
+			if err != nil && scans > 0 {
+				t.Errorf("Query() ran %d scans before refusing", scans)
+			}
+			if err == nil && resp.Stats.AllowFiltering != tt.req.AllowFiltering {
+				t.Errorf("Query() stats allow_filtering = %v, want %v", resp.Stats.AllowFiltering, tt.req.AllowFiltering)
+			}
+		})
+	}
+}
+
+func TestQueryRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int // before the scan succeeds
+		err          error
+		backoff      time.Duration
+		timeout      time.Duration
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "transient", failures: 2, err: unavailableError{}, wantAttempts: 3},
+		{name: "too many failures", failures: 5, err: unavailableError{}, wantErr: true, wantAttempts: 4},
+		{name: "fatal", failures: 1, err: requestError(gocql.ErrCodeSyntax), wantErr: true, wantAttempts: 1},
+		{
+			name:         "deadline",
+			failures:     1,
+			err:          unavailableError{},
+			backoff:      time.Hour,
+			timeout:      time.Minute,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			failures := tt.failures
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if failures > 0 {
+					failures--
+					return nil, tt.err
+				}
+				return nil, nil
+			})
+			cfg := testConfig()
+			cfg.QueryConfig.Retries = 3
+			cfg.QueryConfig.RetryBackoff = time.Millisecond
+			if tt.backoff > 0 {
+				cfg.QueryConfig.RetryBackoff = tt.backoff
+			}
+			s := newTestServer(t, cfg, session)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			_, err := s.Query(ctx, &pb.QueryRequest{Origin: "o"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Query() = %v, want error %v", err, tt.wantErr)
+			}
+			if attempts := len(session.ran("SELECT")); attempts != tt.wantAttempts {
+				t.Errorf("Query() scanned %d times, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestQueryPreviousPeriod(t *testing.T) {
+	var (
+		start = time.Unix(1000, 0)
+		end   = time.Unix(1100, 0)
+		// The previous period is [start-(end-start), start).
+		previousStart = time.Unix(900, 0)
+	)
+	rows := map[int64][][]interface{}{ // by start time
+		start.Unix(): {
+			{"", "a", 30.0, nil, ""},
+			{"", "b", 0.0, int64p(5), ""},
+		},
+		previousStart.Unix(): {
+			{"", "a", 20.0, nil, ""},
+			{"", "c", 7.0, nil, ""},
+		},
+	}
+	baselineRows := [][]interface{}{{"", "a", 10.0, nil, ""}}
+
+	tests := []struct {
+		name     string
+		baseline bool
+		noEnd    bool
+		wantCode twirp.ErrorCode
+		want     []*pb.EventComparison
+	}{
+		{
+			name: "previous period",
+			want: []*pb.EventComparison{
+				{Name: "a", ValueA: float64p(20), ValueB: float64p(30), Difference: 10, Ratio: float64p(1.5), PercentChange: float64p(50)},
+				{Name: "b", ValueB: float64p(5), Difference: 5},
+				{Name: "c", ValueA: float64p(7), Difference: -7},
+			},
+		},
+		{
+			name:     "baseline",
+			baseline: true,
+			want: []*pb.EventComparison{
+				{Name: "a", ValueA: float64p(10), ValueB: float64p(20), Difference: 10, Ratio: float64p(2), PercentChange: float64p(100)},
+				{Name: "b", ValueB: float64p(5), Difference: 5},
+				{Name: "c", ValueA: float64p(7), Difference: -7},
+			},
+		},
+		{name: "open window", noEnd: true, wantCode: twirp.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if q.vals[0] == "base" {
+					return baselineRows, nil
+				}
+				if times := timeVals(q); len(times) > 0 {
+					return rows[times[0].Unix()], nil
+				}
+				return nil, nil
+			})
+			s := newTestServer(t, testConfig(), session)
+
+			req := &pb.QueryRequest{
+				Origin:         "o",
+				StartTime:      timestamppb.New(start),
+				EndTime:        timestamppb.New(end),
+				AllowFiltering: true,
+				PreviousPeriod: true,
+			}
+			if tt.noEnd {
+				req.EndTime = nil
+			}
+			if tt.baseline {
+				req.Baseline = &pb.QueryBaseline{Origin: "base"}
+			}
+			resp, err := s.Query(context.Background(), req)
+			if errorCode(err) != tt.wantCode || (tt.wantCode == "" && err != nil) {
+				t.Fatalf("Query() = %v, want code %q", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+
+			var windows [][]int64
+			for _, q := range session.ran("SELECT") {
+				if times := timeVals(q); len(times) == 2 {
+					windows = append(windows, []int64{times[0].Unix(), times[1].Unix()})
+				}
+			}
+			wantWindows := [][]int64{{start.Unix(), end.Unix()}, {previousStart.Unix(), start.Unix()}}
+			if !reflect.DeepEqual(windows, wantWindows) {
+				t.Errorf("Query() scanned windows %v, want %v", windows, wantWindows)
+			}
+			if len(resp.PreviousPeriod) != len(tt.want) {
+				t.Fatalf("Query() previous period = %v, want %v", resp.PreviousPeriod, tt.want)
+			}
+			for i, c := range resp.PreviousPeriod {
+				if !proto.Equal(c, tt.want[i]) {
+					t.Errorf("Query() comparison %d = %v, want %v", i, c, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQueryDowngradeConsistency(t *testing.T) {
+	tests := []struct {
+		name            string
+		downgrade       bool
+		unavailable     gocql.Consistency // fails at this level and stronger ones
+		wantErr         bool
+		wantConsistency []gocql.Consistency // of the scans
+		wantDegraded    bool
+	}{
+		{name: "available", downgrade: true, unavailable: gocql.All, wantConsistency: []gocql.Consistency{gocql.Quorum}},
+		{
+			name:            "downgraded",
+			downgrade:       true,
+			unavailable:     gocql.Quorum,
+			wantConsistency: []gocql.Consistency{gocql.Quorum, gocql.One},
+			wantDegraded:    true,
+		},
+		{
+			name:            "disabled",
+			unavailable:     gocql.Quorum,
+			wantErr:         true,
+			wantConsistency: []gocql.Consistency{gocql.Quorum},
+		},
+		{
+			name:            "unavailable after downgrading",
+			downgrade:       true,
+			unavailable:     gocql.One,
+			wantErr:         true,
+			wantConsistency: []gocql.Consistency{gocql.Quorum, gocql.One},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if q.consistency >= tt.unavailable {
+					return nil, unavailableError{}
+				}
+				return [][]interface{}{{"", "e", 1.0, nil, ""}}, nil
+			})
+			cfg := testConfig()
+			cfg.QueryConfig.DowngradeConsistency = tt.downgrade
+			cfg.QueryConfig.Retries = 0
+			s := newTestServer(t, cfg, session)
+
+			resp, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Query() = %v, want error %v", err, tt.wantErr)
+			}
+			var consistency []gocql.Consistency
+			for _, q := range session.ran("SELECT") {
+				consistency = append(consistency, q.consistency)
+			}
+			if !reflect.DeepEqual(consistency, tt.wantConsistency) {
+				t.Errorf("Query() scanned at %v, want %v", consistency, tt.wantConsistency)
+			}
+			if err != nil {
+				return
+			}
+			if resp.DegradedConsistency != tt.wantDegraded {
+				t.Errorf("Query() degraded_consistency = %v, want %v", resp.DegradedConsistency, tt.wantDegraded)
+			}
+			if len(resp.Events) != 1 || resp.Events[0].Value != 1 {
+				t.Errorf("Query() events = %v, want e=1", resp.Events)
+			}
+		})
+	}
+}
+
+func TestQueryReadYourWritesFlush(t *testing.T) {
+	tests := []struct {
+		name           string
+		readYourWrites bool
+		wantFlushed    []string // origin/event of the rows flushed before the scan
+		wantBuffered   int
+	}{
+		{name: "buffered", wantBuffered: 3},
+		{name: "read your writes", readYourWrites: true, wantFlushed: []string{"a/e1", "a/e2"}, wantBuffered: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			var trail []string // of inserted rows and scans, in order
+			session.batchErr = func(b *fakeBatch) error {
+				for _, st := range b.stmts {
+					trail = append(trail, fmt.Sprintf("%v/%v", st.vals[2], st.vals[3]))
+				}
+				return nil
+			}
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				trail = append(trail, "SELECT")
+				return nil, nil
+			})
+			s := newTestServer(t, testConfig(), session)
+			s.batchWriter.lastExport = time.Now() // nothing is due to be flushed
+
+			for _, e := range []*pb.Entry{
+				{Origin: "a", Events: []*pb.Event{{Name: "e1", Value: 1}, {Name: "e2", Value: 2}}},
+				{Origin: "b", Events: []*pb.Event{{Name: "e1", Value: 3}}},
+			} {
+				if err := s.batchWriter.Write(e); err != nil {
+					t.Fatalf("Write() = %v", err)
+				}
+			}
+			if _, err := s.Query(context.Background(), &pb.QueryRequest{
+				Origin:         "a",
+				ReadYourWrites: tt.readYourWrites,
+			}); err != nil {
+				t.Fatalf("Query() = %v", err)
+			}
+
+			want := append(tt.wantFlushed, "SELECT")
+			sort.Strings(trail[:len(trail)-1]) // flush order isn't defined
+			if !reflect.DeepEqual(trail, want) {
+				t.Errorf("Query() ran %v, want %v", trail, want)
+			}
+			if n := len(s.batchWriter.events); n != tt.wantBuffered {
+				t.Errorf("Query() left %d events buffered, want %d", n, tt.wantBuffered)
+			}
+			for k := range s.batchWriter.events {
+				if origin, _, _, _, _ := parseKey(k); tt.readYourWrites && origin != "b" {
+					t.Errorf("Query() left %q buffered", k)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *pb.QueryRequest
+		wantScanned int64
+		wantGroups  int64
+	}{
+		{name: "events", req: &pb.QueryRequest{Origin: "o"}, wantScanned: 3, wantGroups: 2},
+		{
+			name:        "baseline",
+			req:         &pb.QueryRequest{Origin: "o", Baseline: &pb.QueryBaseline{Origin: "base"}},
+			wantScanned: 5,
+			wantGroups:  3,
+		},
+		{
+			name: "previous period",
+			req: &pb.QueryRequest{
+				Origin:         "o",
+				StartTime:      timestamppb.New(time.Unix(100, 0)),
+				EndTime:        timestamppb.New(time.Unix(200, 0)),
+				PreviousPeriod: true,
+				AllowFiltering: true,
+			},
+			wantScanned: 4,
+			wantGroups:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if q.vals[0] == "base" {
+					return [][]interface{}{{"", "a", 1.0, nil, ""}, {"", "c", 1.0, nil, ""}}, nil
+				}
+				if times := timeVals(q); len(times) > 0 && times[0].Unix() != 100 {
+					return [][]interface{}{{"", "a", 1.0, nil, ""}}, nil // previous period
+				}
+				return [][]interface{}{
+					{"", "a", 1.0, nil, ""},
+					{"", "a", 2.0, nil, ""},
+					{"", "b", 3.0, nil, ""},
+				}, nil
+			})
+			s := newTestServer(t, testConfig(), session)
+
+			tt.req.WithStats = true
+			resp, err := s.Query(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Query() = %v", err)
+			}
+			if got := resp.Stats.RowsScanned; got != tt.wantScanned {
+				t.Errorf("Query() rows_scanned = %d, want %d", got, tt.wantScanned)
+			}
+			if got := resp.Stats.GroupsReturned; got != tt.wantGroups {
+				t.Errorf("Query() groups_returned = %d, want %d", got, tt.wantGroups)
+			}
+			if resp.Stats.RowsScanned < resp.Stats.GroupsReturned {
+				t.Errorf("Query() scanned %d rows, fewer than the %d groups returned", resp.Stats.RowsScanned, resp.Stats.GroupsReturned)
+			}
+		})
+	}
+}
+
+func TestQueryEventAliases(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *pb.QueryRequest
+		wantScans  []string // of the event names
+		wantEvents []*pb.Event
+	}{
+		{
+			name:       "event",
+			req:        &pb.QueryRequest{Event: "new"},
+			wantScans:  []string{"new", "old"},
+			wantEvents: []*pb.Event{{Name: "new", Value: 3, IntValue: int64p(4)}},
+		},
+		{
+			name:       "origin",
+			req:        &pb.QueryRequest{Origin: "o"},
+			wantEvents: []*pb.Event{{Name: "new", Value: 3, IntValue: int64p(4)}, {Name: "other", Value: 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := map[string][][]interface{}{
+				"new":   {{"", "new", 1.0, nil, ""}, {"", "new", 2.0, nil, ""}},
+				"old":   {{"", "old", 0.0, int64p(4), ""}},
+				"other": {{"", "other", 5.0, nil, ""}},
+			}
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				if name := q.vals[0].(string); name != "o" {
+					return rows[name], nil
+				}
+				var all [][]interface{}
+				for _, name := range []string{"new", "old", "other"} {
+					all = append(all, rows[name]...)
+				}
+				return all, nil
+			})
+			cfg := testConfig()
+			cfg.QueryConfig.EventAliases = map[string]string{"old": "new"}
+			s := newTestServer(t, cfg, session)
+
+			resp, err := s.Query(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Query() = %v", err)
+			}
+			var scans []string
+			if tt.req.Event != "" {
+				for _, q := range session.ran("SELECT") {
+					scans = append(scans, q.vals[0].(string))
+				}
+			}
+			if !reflect.DeepEqual(scans, tt.wantScans) {
+				t.Errorf("Query() scanned %q, want %q", scans, tt.wantScans)
+			}
+			if len(resp.Events) != len(tt.wantEvents) {
+				t.Fatalf("Query() events = %v, want %v", resp.Events, tt.wantEvents)
+			}
+			for i, e := range resp.Events {
+				if !proto.Equal(e, tt.wantEvents[i]) {
+					t.Errorf("Query() event %d = %v, want %v", i, e, tt.wantEvents[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQueryLegacyKeys(t *testing.T) {
+	rows := map[string][][]interface{}{
+		"http:requests": {{"", "http:requests", 1.0, nil, "ms"}, {"", "http:requests", 2.0, nil, ""}},
+		"http_requests": {{"", "http_requests", 4.0, nil, "ms"}, {"", "http_requests", 0.0, int64p(5), "s"}},
+		"a:b":           {{"", "x:y", 1.0, nil, ""}},
+		"a_b":           {{"", "x_y", 2.0, nil, ""}},
+	}
+	tests := []struct {
+		name       string
+		legacyKeys bool
+		req        *pb.QueryRequest
+		wantScans  []string
+		wantEvents []*pb.Event
+	}{
+		{
+			name:       "disabled",
+			req:        &pb.QueryRequest{Event: "http:requests"},
+			wantScans:  []string{"http:requests"},
+			wantEvents: []*pb.Event{{Name: "http:requests", Value: 2}, {Name: "http:requests", Value: 1, Unit: "ms"}},
+		},
+		{
+			name:       "event",
+			legacyKeys: true,
+			req:        &pb.QueryRequest{Event: "http:requests"},
+			wantScans:  []string{"http:requests", "http_requests"},
+			wantEvents: []*pb.Event{
+				{Name: "http:requests", Value: 2},
+				{Name: "http:requests", Value: 5, Unit: "ms"},
+				{Name: "http:requests", IntValue: int64p(5), Unit: "s"},
+			},
+		},
+		{
+			name:       "origin",
+			legacyKeys: true,
+			req:        &pb.QueryRequest{Origin: "a:b"},
+			wantScans:  []string{"a:b", "a_b"},
+			wantEvents: []*pb.Event{{Name: "x:y", Value: 1}, {Name: "x_y", Value: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				return rows[q.vals[0].(string)], nil
+			})
+			cfg := testConfig()
+			cfg.QueryConfig.LegacyKeys = tt.legacyKeys
+			s := newTestServer(t, cfg, session)
+
+			resp, err := s.Query(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Query() = %v", err)
+			}
+			var scans []string
+			for _, q := range session.ran("SELECT") {
+				scans = append(scans, q.vals[0].(string))
+			}
+			if !reflect.DeepEqual(scans, tt.wantScans) {
+				t.Errorf("Query() scanned %q, want %q", scans, tt.wantScans)
+			}
+			if len(resp.Events) != len(tt.wantEvents) {
+				t.Fatalf("Query() events = %v, want %v", resp.Events, tt.wantEvents)
+			}
+			for i, e := range resp.Events {
+				if !proto.Equal(e, tt.wantEvents[i]) {
+					t.Errorf("Query() event %d = %v, want %v", i, e, tt.wantEvents[i])
+				}
+			}
+		})
+	}
+}
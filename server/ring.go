@@ -0,0 +1,49 @@
+package server
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// ringBuffer keeps the last n entries written.
+type ringBuffer struct {
+	mu      sync.Mutex
+	entries []*pb.Entry
+	next    int
+	full    bool
+}
+
+func newRingBuffer(n int) *ringBuffer {
+	return &ringBuffer{entries: make([]*pb.Entry, n)}
+}
+
+// Add adds a copy of the entry to the buffer,
+// discarding the oldest entry if the buffer is full.
+func (r *ringBuffer) Add(e *pb.Entry) {
+	e = proto.Clone(e).(*pb.Entry)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// List returns the buffered entries, oldest first.
+func (r *ringBuffer) List() []*pb.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]*pb.Entry(nil), r.entries[:r.next]...)
+	}
+	entries := make([]*pb.Entry, 0, len(r.entries))
+	entries = append(entries, r.entries[r.next:]...)
+	return append(entries, r.entries[:r.next]...)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		name  string
+		added int
+		want  []string
+	}{
+		{name: "empty", added: 0, want: nil},
+		{name: "partial", added: 2, want: []string{"t0", "t1"}},
+		{name: "full", added: 3, want: []string{"t0", "t1", "t2"}},
+		{name: "wrapped", added: 5, want: []string{"t2", "t3", "t4"}},
+		{name: "wrapped twice", added: 7, want: []string{"t4", "t5", "t6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRingBuffer(3)
+			for i := 0; i < tt.added; i++ {
+				r.Add(&pb.Entry{TraceId: fmt.Sprintf("t%d", i)})
+			}
+			var got []string
+			for _, e := range r.List() {
+				got = append(got, e.TraceId)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRingBufferCopiesEntries(t *testing.T) {
+	r := newRingBuffer(1)
+	e := &pb.Entry{TraceId: "t"}
+	r.Add(e)
+	e.TraceId = "changed"
+	if got := r.List()[0].TraceId; got != "t" {
+		t.Errorf("List() trace ID = %q after changing the added entry, want %q", got, "t")
+	}
+}
+
+func TestListRecentEvents(t *testing.T) {
+	cfg := testConfig()
+	cfg.DebugConfig.RecentEvents = 2
+	s := newTestServer(t, cfg, newFakeSession())
+	for i := 0; i < 3; i++ {
+		entry := &pb.Entry{TraceId: fmt.Sprintf("t%d", i), Origin: "o", Events: []*pb.Event{{Name: "e", Value: 1}}}
+		if _, err := s.InsertEvents(context.Background(), &pb.InsertEventsRequest{Entries: []*pb.Entry{entry}}); err != nil {
+			t.Fatalf("InsertEvents() = %v", err)
+		}
+	}
+
+	resp, err := s.ListRecentEvents(context.Background(), &pb.ListRecentEventsRequest{})
+	if err != nil {
+		t.Fatalf("ListRecentEvents() = %v", err)
+	}
+	var got []string
+	for _, e := range resp.Entries {
+		got = append(got, e.TraceId)
+	}
+	if want := []string{"t1", "t2"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ListRecentEvents() = %v, want %v", got, want)
+	}
+}
+
+func TestListRecentEventsDisabled(t *testing.T) {
+	s := newTestServer(t, testConfig(), newFakeSession())
+	_, err := s.ListRecentEvents(context.Background(), &pb.ListRecentEventsRequest{})
+	if code := errorCode(err); code != twirp.FailedPrecondition {
+		t.Errorf("ListRecentEvents() error code = %q, want %q", code, twirp.FailedPrecondition)
+	}
+}
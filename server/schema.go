@@ -0,0 +1,36 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/mykodev/myko/datastore/cassandra"
+)
+
+// checkSchema periodically checks the events table for drift
+// from the expected schema, logging and counting each check
+// that finds one.
+func (s *Server) checkSchema(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.checkSchemaOnce()
+	}
+}
+
+// checkSchemaOnce checks the events table once, reporting
+// whether it drifted. Failing to check it, e.g. if the
+// cluster is unreachable, isn't counted as a drift.
+func (s *Server) checkSchemaOnce() bool {
+	err := s.session.CheckSchema()
+	if err == nil {
+		return false
+	}
+	if !cassandra.IsSchemaDrift(err) {
+		log.Printf("Failed to check the events table schema: %v", err)
+		return false
+	}
+	log.Printf("Events table schema drifted: %v", err)
+	s.metrics.schemaDrifts.Add(1)
+	return true
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mykodev/myko/datastore/cassandra"
+)
+
+func TestCheckSchemaOnce(t *testing.T) {
+	var (
+		changedType   = fmt.Errorf(`%w of "value": float, want double`, cassandra.ErrColumnType)
+		missingColumn = fmt.Errorf(`%w "unit"`, cassandra.ErrMissingColumn)
+		unreachable   = errors.New("failed to fetch keyspace metadata")
+	)
+	tests := []struct {
+		name       string
+		errs       []error // of the consecutive checks
+		wantDrifts int64
+	}{
+		{name: "unchanged", errs: []error{nil, nil}},
+		{name: "changed type", errs: []error{changedType}, wantDrifts: 1},
+		{name: "drifted twice", errs: []error{missingColumn, nil, missingColumn}, wantDrifts: 2},
+		{name: "dropped table", errs: []error{cassandra.ErrMissingTable}, wantDrifts: 1},
+		{name: "unreachable", errs: []error{unreachable, changedType, unreachable}, wantDrifts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			s := newTestServer(t, testConfig(), session)
+
+			for _, err := range tt.errs {
+				session.mu.Lock()
+				session.schemaErr = err
+				session.mu.Unlock()
+				drift := err != nil && err != unreachable
+				if got := s.checkSchemaOnce(); got != drift {
+					t.Errorf("checkSchemaOnce() = %v with %v, want %v", got, err, drift)
+				}
+			}
+			if got := s.metrics.schemaDrifts.Value(); got != tt.wantDrifts {
+				t.Errorf("schema_drifts = %d, want %d", got, tt.wantDrifts)
+			}
+		})
+	}
+}
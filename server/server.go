@@ -2,199 +2,1537 @@ package server
 
 import (
 	"context"
+	"expvar"
+	"fmt"
 	"log"
+	"math"
+	"math/rand"
 	"sort"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/mykodev/myko/config"
+	"github.com/mykodev/myko/datastore"
 	"github.com/mykodev/myko/datastore/cassandra"
 	"github.com/mykodev/myko/format"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/mykodev/myko/proto"
 )
 
 type Server struct {
 	keyspace    string
-	session     *cassandra.Session
+	ttl         time.Duration
+	eventTTLs   *eventTTLs
+	safeMode    bool
+	deletes     config.DeleteConfig
+	session     datastore.Session
+	reads       datastore.Session // for queries, may be session
 	batchWriter *batchWriter
+
+	aliases   map[string]string   // old event name -> new event name
+	aliasesOf map[string][]string // new event name -> old event names
+
+	parallelism int
+	legacyKeys  bool
+	maxTrace    int
+	units       map[string]config.Unit
+
+	fanOutKeyspaces   map[string]bool
+	fanOutParallelism int
+	slowQueries       slowQueryLog
+
+	downgradeConsistency bool
+	queryRetries         int
+	queryRetryBackoff    time.Duration
+
+	unitLimiter        *unitLimiter // nil if disabled
+	rejectUnitOverflow bool
+	defaultUnits       map[string]map[string]string // origin -> name -> unit
+	requireUnits       bool
+	maxAbsValue        float64
+	clampValues        bool
+	catalog            map[string]config.CatalogEvent
+	catalogMode        string
+	normalization      config.NameNormalization
+	sampleRate         float64
+
+	limiter        *requestLimiter // nil if disabled
+	async          *asyncInserter
+	tenants        tenantResolver // nil if disabled
+	tenantRequired bool
+
+	expiryNotifier *expiryNotifier // nil if disabled
+	snapshotDir    string          // empty if disabled
+
+	ready atomic.Bool
+
+	pipeline     []Stage
+	customStages []namedStage
+
+	registry Registry
+	metrics  *metrics
 }
 
-func New(cfg config.Config) (*Server, error) {
-	cassandraConfig := cfg.DataConfig.CassandraConfig
-	session, err := cassandra.NewSession(cassandraConfig)
-	if err != nil {
+func New(cfg config.Config, opts ...Option) (*Server, error) {
+	cfg = cfg.Resolved()
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
+	switch cfg.FlushConfig.Order {
+	case flushOrderNone, flushOrderKey, flushOrderShuffle:
+	default:
+		return nil, fmt.Errorf("unknown flush order %q", cfg.FlushConfig.Order)
+	}
+	switch cfg.IngestConfig.CatalogMode {
+	case "", catalogStrict, catalogLenient:
+	default:
+		return nil, fmt.Errorf("unknown catalog mode %q", cfg.IngestConfig.CatalogMode)
+	}
+	for name, c := range cfg.IngestConfig.Catalog {
+		switch c.Type {
+		case "", "int", "float":
+		default:
+			return nil, fmt.Errorf("unknown type %q of catalog event %q", c.Type, name)
+		}
+	}
+	if n := cfg.IngestConfig.NameNormalization; n.Separators != "" && n.Separator == "" {
+		return nil, fmt.Errorf("separators %q have no separator to be replaced with", n.Separators)
+	}
+	if r := cfg.IngestConfig.SampleRate; r < 0 || r > 1 {
+		return nil, fmt.Errorf("sample rate %v is not in [0, 1]", r)
+	}
+	switch cfg.DeleteConfig.Strategy {
+	case deleteImmediate, deleteBatch, deleteExpire:
+	default:
+		return nil, fmt.Errorf("unknown delete strategy %q", cfg.DeleteConfig.Strategy)
+	}
+	cassandraConfig := cfg.DataConfig.CassandraConfig
 	server := &Server{
 		keyspace: cassandraConfig.Keyspace,
-		session:  session,
+		safeMode: cfg.SafeMode,
+		deletes:  cfg.DeleteConfig,
+
+		aliases:   cfg.QueryConfig.EventAliases,
+		aliasesOf: make(map[string][]string),
+
+		parallelism: cfg.QueryConfig.Parallelism,
+		legacyKeys:  cfg.QueryConfig.LegacyKeys,
+		maxTrace:    cfg.QueryConfig.MaxTraceEvents,
+		units:       cfg.QueryConfig.Units,
+
+		fanOutKeyspaces:   make(map[string]bool),
+		fanOutParallelism: cfg.QueryConfig.FanOutParallelism,
+
+		downgradeConsistency: cfg.QueryConfig.DowngradeConsistency,
+		queryRetries:         cfg.QueryConfig.Retries,
+		queryRetryBackoff:    cfg.QueryConfig.RetryBackoff,
+		slowQueries: slowQueryLog{
+			threshold:  cfg.QueryConfig.SlowQueryThreshold,
+			sampleRate: cfg.QueryConfig.SlowQuerySampleRate,
+		},
+
+		rejectUnitOverflow: cfg.IngestConfig.RejectUnitOverflow,
+		defaultUnits:       cfg.IngestConfig.DefaultUnits,
+		requireUnits:       cfg.IngestConfig.RequireUnits,
+		maxAbsValue:        cfg.IngestConfig.MaxAbsValue,
+		clampValues:        cfg.IngestConfig.ClampValues,
+		catalog:            cfg.IngestConfig.Catalog,
+		catalogMode:        cfg.IngestConfig.CatalogMode,
+		normalization:      cfg.IngestConfig.NameNormalization,
+		sampleRate:         cfg.IngestConfig.SampleRate,
+	}
+	var err error
+	server.eventTTLs, err = newEventTTLs(cassandraConfig.TTL, cassandraConfig.EventTTLs)
+	if err != nil {
+		return nil, err
+	}
+	server.fanOutKeyspaces[cassandraConfig.Keyspace] = true
+	for _, ks := range cfg.QueryConfig.FanOutKeyspaces {
+		server.fanOutKeyspaces[ks] = true
+	}
+	for _, opt := range opts {
+		opt(server)
+	}
+	if server.session == nil {
+		if err := server.connect(cassandraConfig); err != nil {
+			return nil, err
+		}
+	}
+	if server.registry == nil {
+		server.registry = new(expvar.Map)
+	}
+	server.metrics = newMetrics(server.registry)
+	server.limiter = newRequestLimiter(cfg.MaxInFlight, cfg.MaxQueued, server.metrics)
+	server.tenants, err = newTenantResolver(cfg.TenantConfig)
+	if err != nil {
+		return nil, err
+	}
+	server.tenantRequired = cfg.TenantConfig.Required
+	server.pipeline, err = server.newPipeline(cfg.IngestConfig.Pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := cfg.IngestConfig.MaxUnitsPerEvent; n > 0 {
+		server.unitLimiter = newUnitLimiter(n)
+	}
+	for old, name := range server.aliases {
+		server.aliasesOf[name] = append(server.aliasesOf[name], old)
+	}
+	server.batchWriter = newBatchWriter(server, cfg.FlushConfig)
+	if n := cfg.DebugConfig.RecentEvents; n > 0 {
+		server.batchWriter.recent = newRingBuffer(n)
+	}
+	server.async = newAsyncInserter(server, cfg.IngestConfig.AsyncWorkers, cfg.IngestConfig.AsyncQueueSize)
+	server.snapshotDir = cfg.DebugConfig.SnapshotDir
+	if interval := cfg.ExpiryConfig.CheckInterval; interval > 0 {
+		server.expiryNotifier = newExpiryNotifier(server, cassandraConfig.TTL, cfg.ExpiryConfig.WebhookURL)
+		go server.expiryNotifier.Run(interval)
+	}
+	if interval := cassandraConfig.SchemaCheckInterval; interval > 0 {
+		go server.checkSchema(interval)
+	}
+	if cassandraConfig.WarmUp {
+		go server.warmUp()
+	} else {
+		server.ready.Store(true)
 	}
-	server.batchWriter = newBatchWriter(server, cfg.FlushConfig.BufferSize, cfg.FlushConfig.Interval)
 	return server, nil
 }
 
+// Close stops accepting async inserts, waits for the queued ones
+// to be inserted and flushes the buffered events. The server
+// shouldn't be used after Close.
+func (s *Server) Close() error {
+	s.async.close()
+	return s.batchWriter.Flush()
+}
+
+// WithSessions makes the server use the given sessions instead of
+// connecting to the configured Cassandra cluster. Queries are read
+// with reads, which may be nil to read with session.
+func WithSessions(session, reads datastore.Session) Option {
+	return func(s *Server) {
+		s.session = session
+		s.reads = reads
+		if reads == nil {
+			s.reads = session
+		}
+	}
+}
+
+// connect creates the sessions to the Cassandra cluster.
+func (s *Server) connect(c config.CassandraConfig) error {
+	session, err := cassandra.NewSession(c)
+	if err != nil {
+		return err
+	}
+	s.session, s.reads = session, session
+	if c.AnalyticsDatacenter != "" {
+		s.reads, err = cassandra.NewAnalyticsSession(c)
+		if err != nil {
+			return fmt.Errorf("failed to create the analytics session: %v", err)
+		}
+	}
+	return nil
+}
+
+// warmUp prepares the statements the server runs, so the
+// first requests don't pay the preparation latency.
+func (s *Server) warmUp() {
+	start := time.Now()
+	if err := s.prepare(); err != nil {
+		// The server is still usable, at the cost of
+		// slower first requests.
+		log.Printf("Failed to warm up: %v", err)
+	} else {
+		log.Printf("Warmed up in %v", time.Since(start))
+	}
+	s.ready.Store(true)
+}
+
+// prepare prepares the statements on each session of the server.
+func (s *Server) prepare() error {
+	sessions := []datastore.Session{s.session}
+	if s.reads != s.session {
+		sessions = append(sessions, s.reads)
+	}
+	for _, session := range sessions {
+		for _, stmt := range s.statements() {
+			if err := session.Prepare(stmt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// statements returns the statements run by the server. Scans are
+// only returned for the common filters, restricting a single
+// column and optionally the creation time.
+func (s *Server) statements() []string {
+	stmts := []string{insertStmt, deleteStmt, hasEventsStmt}
+	if s.deletes.Strategy == deleteExpire {
+		stmts = append(stmts, expireSelectStmt, expireInsertStmt)
+	}
+	if s.batchWriter.merger != nil {
+		stmts = append(stmts, mergeStmt)
+	}
+	where, _, _ := cassandra.Filter{TraceID: "-"}.CQL()
+	stmts = append(stmts, s.traceStmt(where))
+
+	rangeCQL, _ := cassandra.TokenRange{}.CQL()
+	for _, f := range []cassandra.Filter{{TraceID: "-"}, {Origin: "-"}, {Event: "-"}} {
+		for _, timed := range []bool{false, true} {
+			if timed {
+				f.Start, f.End = time.Unix(0, 0), time.Unix(1, 0)
+			}
+			where, _, _ := f.CQL()
+			scan := where
+			if s.parallelism >= 2 {
+				scan += " AND " + rangeCQL
+			}
+			stmts = append(stmts, scanStmt(scan), s.selectIDsStmt(where))
+		}
+	}
+	return stmts
+}
+
+// Ready reports whether the server is ready to serve requests.
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
 func (s *Server) Query(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
-	filter := cassandra.Filter{
-		TraceID: req.TraceId,
-		Origin:  req.Origin,
-		Event:   req.Event,
+	start := time.Now()
+	opts := scanOptions{ctx: ctx, localDC: req.LocalDc, scanned: new(atomic.Int64)}
+	if s.downgradeConsistency {
+		opts.degraded = new(atomic.Bool)
 	}
-	filterCQL, err := filter.CQL()
+	filter, err := cassandra.NewFilter().
+		WithTraceID(req.TraceId).
+		WithOrigin(req.Origin).
+		WithEvent(s.normalizeName(req.Event)).
+		WithTimeRange(timeOf(req.StartTime), timeOf(req.EndTime)).
+		Build()
 	if err != nil {
-		return nil, err
+		return nil, twirp.NewError(twirp.InvalidArgument, err.Error())
+	}
+	var baselineFilter *cassandra.Filter
+	if b := req.Baseline; b != nil {
+		f, err := cassandra.NewFilter().
+			WithTraceID(b.TraceId).
+			WithOrigin(b.Origin).
+			WithEvent(s.normalizeName(b.Event)).
+			WithTimeRange(timeOf(b.StartTime), timeOf(b.EndTime)).
+			Build()
+		if err != nil {
+			return nil, twirp.InvalidArgumentError("baseline", err.Error())
+		}
+		baselineFilter = &f
+	}
+
+	filtering := filter.NeedsFiltering() || (baselineFilter != nil && baselineFilter.NeedsFiltering())
+	if filtering && !req.AllowFiltering {
+		return nil, twirp.NewError(twirp.FailedPrecondition,
+			"query may scan the entire table, set allow_filtering to run it")
+	}
+	if req.WithVariance {
+		if req.ConvertToUnit != "" {
+			return nil, twirp.InvalidArgumentError("with_variance", "cannot be combined with convert_to_unit")
+		}
+		opts.dispersion = newDispersion()
+	}
+	// The baseline and the previous period don't contribute
+	// to the dispersion of the queried events.
+	otherOpts := opts
+	otherOpts.dispersion = nil
+
+	var expr valueExpr
+	if req.ValueExpression != "" {
+		e, err := parseValueExpr(req.ValueExpression)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError("value_expression", err.Error())
+		}
+		expr = e
+	}
+
+	var previousFilter *cassandra.Filter
+	if req.PreviousPeriod {
+		if filter.Start.IsZero() || filter.End.IsZero() {
+			return nil, twirp.InvalidArgumentError("previous_period", "needs both start_time and end_time")
+		}
+		// The window is shifted by its exact length, timestamps
+		// are absolute so daylight saving time doesn't apply.
+		f := filter
+		f.Start, f.End = filter.Start.Add(-filter.End.Sub(filter.Start)), filter.Start
+		previousFilter = &f
+	}
+	if req.ReadYourWrites {
+		// Flushed events are written at the consistency level
+		// of the primary session, reading with it at the same
+		// level is enough to observe them. Local, analytics and
+		// downgraded reads may miss them.
+		for _, o := range []*scanOptions{&opts, &otherOpts} {
+			o.localDC = false
+			o.primary = true
+			o.degraded = nil
+		}
+		filters := []cassandra.Filter{filter}
+		if baselineFilter != nil {
+			filters = append(filters, *baselineFilter)
+		}
+		if err := s.batchWriter.FlushMatching(s.matchesAny(filters)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(req.Keyspaces) > 0 {
+		seen := make(map[string]bool, len(req.Keyspaces))
+		for _, ks := range req.Keyspaces {
+			if !s.fanOutKeyspaces[ks] {
+				return nil, twirp.InvalidArgumentError("keyspaces", fmt.Sprintf("%q is not a fan-out keyspace", ks))
+			}
+			if seen[ks] {
+				// It would be counted twice.
+				return nil, twirp.InvalidArgumentError("keyspaces", fmt.Sprintf("%q is listed more than once", ks))
+			}
+			seen[ks] = true
+		}
+		opts.fanOut = &fanOut{keyspaces: req.Keyspaces}
 	}
 
-	q, err := s.session.Query(`
-		SELECT event, value, unit 
-		FROM {{.Keyspace}}.events ` + filterCQL + ` ALLOW FILTERING`)
+	aggregate := s.aggregate
+	if req.PerTrace {
+		aggregate = s.aggregatePerTrace
+	}
+	v, err := aggregate(opts, filter)
 	if err != nil {
 		return nil, err
 	}
+	var baseline map[string]*pb.Event
+	if baselineFilter != nil {
+		baseline, err = aggregate(otherOpts, *baselineFilter)
+		if err != nil {
+			return nil, err
+		}
+		if err := subtractEvents(v, baseline); err != nil {
+			return nil, err
+		}
+	}
+	if req.ConvertToUnit != "" {
+		v, err = convertUnits(s.units, v, req.ConvertToUnit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var previous []*pb.EventComparison
+	if previousFilter != nil {
+		pv, err := aggregate(otherOpts, *previousFilter)
+		if err != nil {
+			return nil, err
+		}
+		if baseline != nil {
+			// Both periods are compared relative to the baseline.
+			if err := subtractEvents(pv, baseline); err != nil {
+				return nil, err
+			}
+		}
+		if req.ConvertToUnit != "" {
+			pv, err = convertUnits(s.units, pv, req.ConvertToUnit)
+			if err != nil {
+				return nil, err
+			}
+		}
+		previous = compareEvents(pv, v)
+	}
 
 	var (
-		name  string
-		unit  string
-		value float64
+		events    []*pb.Event
+		nonFinite int64
 	)
+	for _, e := range v {
+		event := &pb.Event{
+			Name:     e.Name,
+			Unit:     e.Unit,
+			Value:    e.Value,
+			IntValue: e.IntValue,
+		}
+		if expr != nil {
+			event.Value = expr(totalValue(e))
+			event.IntValue = nil
+			if math.IsNaN(event.Value) || math.IsInf(event.Value, 0) {
+				// e.g. log(0), it is left out and counted
+				// rather than failing the whole query.
+				nonFinite++
+				continue
+			}
+		}
+		if opts.dispersion != nil {
+			if variance, ok := opts.dispersion.variance(groupKey(e.Name, e.Unit)); ok {
+				stdDev := math.Sqrt(variance)
+				event.Variance = &variance
+				event.StdDev = &stdDev
+			}
+		}
+		if req.WithCount {
+			// Count is the number of rows contributed to the sum,
+			// it allows clients to merge results from multiple servers.
+			event.Count = e.Count
+		}
+		events = append(events, event)
+	}
+
+	sorter := &eventSorter{events: events}
+	sort.Sort(sorter)
+
+	resp := &pb.QueryResponse{
+		Events:              sorter.events,
+		PreviousPeriod:      previous,
+		DegradedConsistency: opts.degraded != nil && opts.degraded.Load(),
+		NonFiniteValues:     nonFinite,
+	}
+	if opts.fanOut != nil {
+		resp.FailedKeyspaces = opts.fanOut.Failed()
+	}
+	var names []string
+	if filter.Event != "" {
+		names = append([]string{filter.Event}, s.aliasesOf[filter.Event]...)
+	}
+	if ttl := s.eventTTLs.shortest(names); ttl > 0 {
+		// Rows older than the TTL of the queried events may
+		// have expired, the results only cover the events
+		// created since.
+		earliest := start.Add(-ttl)
+		resp.EarliestTime = timestamppb.New(earliest)
+		resp.StartTimeExpired = !filter.Start.IsZero() && filter.Start.Before(earliest)
+	}
+	if len(req.HistogramBounds) > 0 {
+		bins, err := histogram(resp.Events, req.HistogramBounds)
+		if err != nil {
+			return nil, err
+		}
+		resp.Histogram = bins
+	}
+	latency := time.Since(start)
+	stats := &pb.QueryStats{
+		RowsScanned:    opts.scanned.Load(),
+		GroupsReturned: int64(len(resp.Events)),
+		AllowFiltering: filtering,
+		LatencyMs:      float64(latency) / float64(time.Millisecond),
+	}
+	if req.WithStats {
+		resp.Stats = stats
+	}
+	if req.WithHash {
+		hash, err := responseHash(resp)
+		if err != nil {
+			return nil, err
+		}
+		resp.Hash = hash
+	}
+	s.slowQueries.log(filter, stats, latency)
+	return resp, nil
+}
+
+// scanOptions are the per-request options of a scan.
+type scanOptions struct {
+	// ctx is the context of the request, nil if there is none.
+	ctx context.Context
+
+	// localDC reads with LOCAL_QUORUM instead of
+	// the consistency level of the session.
+	localDC bool
+
+	// primary reads with the session the events are written
+	// with instead of the one for queries.
+	primary bool
+
+	// traces collects the trace IDs of the scanned rows if non-nil.
+	traces *traceSet
+
+	// keyspace is the keyspace to scan, empty for the
+	// keyspace of the session.
+	keyspace string
+
+	// fanOut fans the scan out across keyspaces if non-nil.
+	fanOut *fanOut
+
+	// dispersion collects the dispersion of the scanned
+	// row values if non-nil.
+	dispersion *dispersion
+
+	// degraded enables downgrading the consistency level if
+	// there are not enough replicas, and records whether any
+	// scan was downgraded.
+	degraded *atomic.Bool
+
+	// consistency overrides the consistency level if non-nil.
+	consistency *gocql.Consistency
+
+	// scanned counts the rows read by the scans if non-nil,
+	// including the ones of failed attempts.
+	scanned *atomic.Int64
+}
+
+// aggregate returns the events matching the filter
+// aggregated by event name and unit. Scans limited to
+// the local datacenter are retried across datacenters if
+// there are not enough local replicas.
+func (s *Server) aggregate(opts scanOptions, filter cassandra.Filter) (map[string]*pb.Event, error) {
+	if opts.fanOut != nil {
+		return s.aggregateKeyspaces(opts, filter)
+	}
+	v, err := s.aggregateOnce(opts, filter)
+	if err != nil && opts.localDC && cassandra.IsUnavailable(err) {
+		log.Printf("Not enough replicas in the local datacenter, escalating: %v", err)
+		opts.localDC = false
+		v, err = s.aggregateOnce(opts, filter)
+	}
+	if err != nil && opts.degraded != nil && cassandra.IsUnavailable(err) {
+		cl, ok := cassandra.Downgrade(s.reads.Consistency())
+		if !ok {
+			return nil, err
+		}
+		log.Printf("Not enough replicas, downgrading to %v: %v", cl, err)
+		opts.consistency = &cl
+		v, err = s.aggregateOnce(opts, filter)
+		if err == nil {
+			opts.degraded.Store(true)
+		}
+	}
+	return v, err
+}
+
+func (s *Server) aggregateOnce(opts scanOptions, filter cassandra.Filter) (map[string]*pb.Event, error) {
+	v, err := s.aggregateAttempt(opts, filter)
+	for i := 0; i < s.queryRetries && cassandra.IsRetryable(err); i++ {
+		backoff := s.queryRetryBackoff << i
+		if opts.ctx != nil {
+			if deadline, ok := opts.ctx.Deadline(); ok && time.Until(deadline) < backoff {
+				return nil, err
+			}
+		}
+		log.Printf("Query failed, retrying in %v: %v", backoff, err)
+		if err := sleep(opts.ctx, backoff); err != nil {
+			return nil, err
+		}
+		v, err = s.aggregateAttempt(opts, filter)
+	}
+	return v, err
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) aggregateAttempt(opts scanOptions, filter cassandra.Filter) (map[string]*pb.Event, error) {
+	parent := opts.dispersion
+	if parent == nil {
+		return s.aggregateFilters(opts, filter)
+	}
+	// Collect into a separate dispersion, so a failed
+	// attempt that is retried isn't counted twice.
+	opts.dispersion = newDispersion()
+	v, err := s.aggregateFilters(opts, filter)
+	if err != nil {
+		return nil, err
+	}
+	parent.merge(opts.dispersion)
+	return v, nil
+}
+
+func (s *Server) aggregateFilters(opts scanOptions, filter cassandra.Filter) (map[string]*pb.Event, error) {
+	filters := []cassandra.Filter{filter}
+	if filter.Event != "" {
+		// Old names of the event need to be scanned separately.
+		for _, old := range s.aliasesOf[filter.Event] {
+			f := filter
+			f.Event = old
+			filters = append(filters, f)
+		}
+	}
 
 	v := make(map[string]*pb.Event)
-	for q.Iter().Scan(&name, &value, &unit) {
-		k := key(req.TraceId, req.Origin, name, unit)
-		event, ok := v[k]
-		if ok {
-			event.Value += value
-			v[k] = event
+	for _, f := range filters {
+		if err := s.scan(opts, f, v); err != nil {
+			return nil, err
+		}
+		if s.legacyKeys {
+			if err := s.scanLegacy(opts, f, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// scan aggregates the events matching the filter into v.
+func (s *Server) scan(opts scanOptions, filter cassandra.Filter, v map[string]*pb.Event) error {
+	where, vals, err := filter.CQL()
+	if err != nil {
+		return err
+	}
+	if s.parallelism < 2 {
+		return s.scanCQL(opts, filter, where, vals, v)
+	}
+
+	ranges := cassandra.SplitTokenRanges(s.parallelism)
+	results := make([]map[string]*pb.Event, len(ranges))
+	errs := make([]error, len(ranges))
+
+	var wg sync.WaitGroup
+	for i, r := range ranges {
+		wg.Add(1)
+		go func(i int, r cassandra.TokenRange) {
+			defer wg.Done()
+			results[i] = make(map[string]*pb.Event)
+			rangeCQL, rangeVals := r.CQL()
+			errs[i] = s.scanCQL(opts, filter, where+" AND "+rangeCQL, append(vals[:len(vals):len(vals)], rangeVals...), results[i])
+		}(i, r)
+	}
+	wg.Wait()
+
+	for i := range ranges {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		if err := mergeEvents(v, results[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanLegacy aggregates the events written with the legacy
+// escaping that match the filter into v. Event names are only
+// restored if the filter is scoped to an event.
+func (s *Server) scanLegacy(opts scanOptions, filter cassandra.Filter, v map[string]*pb.Event) error {
+	legacy := filter
+	legacy.TraceID = format.Escape(filter.TraceID)
+	legacy.Origin = format.Escape(filter.Origin)
+	legacy.Event = format.Escape(filter.Event)
+	if legacy == filter {
+		return nil // already scanned
+	}
+
+	lv := make(map[string]*pb.Event)
+	if err := s.scan(opts, legacy, lv); err != nil {
+		return err
+	}
+	for _, e := range lv {
+		if filter.Event != "" {
+			e.Name = s.eventName(filter.Event)
+		}
+		k := groupKey(e.Name, e.Unit)
+		if event, ok := v[k]; ok {
+			if err := addValue(event, e); err != nil {
+				return err
+			}
 		} else {
-			v[k] = &pb.Event{
-				Name:  name,
-				Value: value,
-				Unit:  unit,
+			v[k] = e
+		}
+	}
+	return nil
+}
+
+// scanStmt returns the statement scanning the rows
+// restricted by the WHERE clause.
+func scanStmt(where string) string {
+	return `
+		SELECT trace_id, event, value, int_value, unit
+		FROM {{.Keyspace}}.events ` + where + ` ALLOW FILTERING`
+}
+
+func (s *Server) scanCQL(opts scanOptions, filter cassandra.Filter, where string, vals []interface{}, v map[string]*pb.Event) error {
+	session := s.reads
+	if opts.primary {
+		session = s.session
+	}
+	if opts.keyspace != "" {
+		session = session.InKeyspace(opts.keyspace)
+	}
+	q, err := session.Query(scanStmt(where), vals...)
+	if err != nil {
+		return err
+	}
+	if opts.localDC {
+		q.Consistency(gocql.LocalQuorum)
+	}
+	if opts.consistency != nil {
+		q.Consistency(*opts.consistency)
+	}
+	if opts.ctx != nil {
+		q = q.WithContext(opts.ctx)
+	}
+
+	var (
+		traceID  string
+		name     string
+		unit     string
+		value    float64
+		intValue *int64
+	)
+
+	iter := q.Iter()
+	for iter.Scan(&traceID, &name, &value, &intValue, &unit) {
+		if opts.scanned != nil {
+			opts.scanned.Add(1)
+		}
+		if opts.traces != nil && traceID != "" {
+			opts.traces.add(traceID)
+		}
+		name = s.eventName(name)
+		k := groupKey(name, unit)
+		if opts.dispersion != nil {
+			v := value
+			if intValue != nil {
+				v += float64(*intValue)
 			}
+			opts.dispersion.add(k, v)
+		}
+		e := &pb.Event{
+			Name:     name,
+			Value:    value,
+			IntValue: intValue,
+			Unit:     unit,
+			Count:    1,
 		}
+		if event, ok := v[k]; ok {
+			if err := addValue(event, e); err != nil {
+				iter.Close()
+				return err
+			}
+		} else {
+			v[k] = e
+		}
+		intValue = nil
 	}
-	var events []*pb.Event
-	for _, e := range v {
-		events = append(events, &pb.Event{
-			Name:  e.Name,
-			Unit:  e.Unit,
-			Value: e.Value,
-		})
+	return iter.Close()
+}
+
+// timeOf returns the time of ts, or the zero time if ts is nil.
+func timeOf(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
 	}
+	return ts.AsTime()
+}
 
-	sorter := &eventSorter{events: events}
-	sort.Sort(sorter)
-	return &pb.QueryResponse{Events: sorter.events}, nil
+// eventName returns the name the event is reported as.
+func (s *Server) eventName(name string) string {
+	if alias, ok := s.aliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// matchesAny returns a function reporting whether the buffered
+// event with the given key may match any of the filters. Time
+// bounds are ignored.
+func (s *Server) matchesAny(filters []cassandra.Filter) func(key string) bool {
+	return func(key string) bool {
+		origin, traceID, name, _, _ := parseKey(key)
+		for _, f := range filters {
+			if (f.Origin == "" || f.Origin == origin) &&
+				(f.TraceID == "" || f.TraceID == traceID) &&
+				(f.Event == "" || f.Event == s.eventName(name)) {
+				return true
+			}
+		}
+		return false
+	}
 }
 
 func (s *Server) InsertEvents(ctx context.Context, req *pb.InsertEventsRequest) (*pb.InsertEventsResponse, error) {
-	for _, entry := range req.Entries {
-		if err := s.batchWriter.Write(format.Espace(entry)); err != nil {
+	mode := writeModeFromContext(ctx)
+	if mode == writeModeAsync {
+		// The queued inserts would be dropped, tell the
+		// client while it can still retry.
+		if s.batchWriter.Paused() {
+			return nil, errPaused
+		}
+		// Buffering may wait for a flush holding the buffer,
+		// do all the work after responding.
+		if err := s.async.enqueue(req); err != nil {
 			return nil, err
 		}
+		return &pb.InsertEventsResponse{}, nil
+	}
+	if err := s.insertEvents(mode, req); err != nil {
+		return nil, err
 	}
 	return &pb.InsertEventsResponse{}, nil
 }
 
-func (s *Server) DeleteEvents(ctx context.Context, req *pb.DeleteEventsRequest) (*pb.DeleteEventsResponse, error) {
-	filter := cassandra.Filter{
-		TraceID: req.TraceId,
-		Origin:  req.Origin,
-		Event:   req.Event,
+func (s *Server) insertEvents(mode writeMode, req *pb.InsertEventsRequest) error {
+	if s.batchWriter.Paused() {
+		return errPaused
 	}
-	filterCQL, err := filter.CQL()
+	entries, err := s.process(req.Entries)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if s.expiryNotifier != nil {
+		for _, entry := range entries {
+			s.expiryNotifier.Seen(entry.Origin)
+		}
+	}
+	if mode == writeModeSync {
+		return s.batchWriter.WriteSync(entries)
+	}
+	for _, entry := range entries {
+		if err := s.batchWriter.Write(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDefaultUnits sets the default unit of the events
+// inserted without a unit.
+func (s *Server) applyDefaultUnits(entries []*pb.Entry) error {
+	for _, entry := range entries {
+		for _, event := range entry.Events {
+			if event.Unit != "" {
+				continue
+			}
+			if unit, ok := s.defaultUnits[entry.Origin][event.Name]; ok {
+				event.Unit = unit
+				continue
+			}
+			if s.requireUnits {
+				return twirp.InvalidArgumentError("unit", fmt.Sprintf("no unit given for event %q", event.Name))
+			}
+		}
 	}
+	return nil
+}
 
-	q, err := s.session.Query(`SELECT id FROM {{.Keyspace}}.events ` + filterCQL + ` ALLOW FILTERING`)
+// limitUnits enforces the distinct unit limit on the events
+// of the given entries.
+func (s *Server) limitUnits(entries []*pb.Entry) error {
+	if s.unitLimiter == nil {
+		return nil
+	}
+	for _, entry := range entries {
+		for _, event := range entry.Events {
+			if s.unitLimiter.admit(event.Name, event.Unit) {
+				continue
+			}
+			s.metrics.unitLimitViolations.Add(1)
+			if s.rejectUnitOverflow {
+				return twirp.InvalidArgumentError("unit", fmt.Sprintf("too many distinct units for event %q", event.Name))
+			}
+			event.Unit = overflowUnit
+		}
+	}
+	return nil
+}
+
+func (s *Server) DeleteEvents(ctx context.Context, req *pb.DeleteEventsRequest) (*pb.DeleteEventsResponse, error) {
+	if err := s.checkDeletesAllowed(); err != nil {
+		return nil, err
+	}
+	ids, err := s.deleteIDs(req)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.deleteRows(ids); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteEventsResponse{Deleted: int64(len(ids))}, nil
+}
 
-	var id gocql.UUID
-	for q.Iter().Scan(&id) {
-		// TODO: Replace deletion with TTL on events table.
-		log.Printf("Deleting %q", id)
-
-		q, err := s.session.Query(`DELETE FROM {{.Keyspace}}.events WHERE id = ?`, id)
+// deleteIDs returns the ids of the rows to delete for the request.
+// They are either given explicitly or selected by the filter, but
+// not both.
+func (s *Server) deleteIDs(req *pb.DeleteEventsRequest) ([]gocql.UUID, error) {
+	if len(req.Ids) == 0 {
+		filter, err := cassandra.NewFilter().
+			WithTraceID(req.TraceId).
+			WithOrigin(req.Origin).
+			WithEvent(s.normalizeName(req.Event)).
+			Build()
 		if err != nil {
+			return nil, twirp.NewError(twirp.InvalidArgument, err.Error())
+		}
+		return s.selectIDs(filter)
+	}
+	if req.TraceId != "" || req.Origin != "" || req.Event != "" {
+		return nil, twirp.InvalidArgumentError("ids", "cannot be combined with a filter")
+	}
+	if max := s.deletes.MaxIDs; max > 0 && len(req.Ids) > max {
+		return nil, twirp.InvalidArgumentError("ids", fmt.Sprintf("lists more than %d ids", max))
+	}
+	ids := make([]gocql.UUID, 0, len(req.Ids))
+	for _, v := range req.Ids {
+		id, err := gocql.ParseUUID(v)
+		if err != nil {
+			return nil, twirp.InvalidArgumentError("ids", fmt.Sprintf("%q is not a valid id", v))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ReplaceEvents deletes the events matching the delete filter and
+// inserts the given entries in a single logged batch. Cassandra
+// guarantees that either all or none of the statements in a logged
+// batch are eventually applied, but readers may observe a partially
+// applied batch while it is being executed since the rows live in
+// different partitions. The entries are validated like the ones of
+// InsertEvents and the rows are removed with the delete strategy.
+func (s *Server) ReplaceEvents(ctx context.Context, req *pb.ReplaceEventsRequest) (*pb.ReplaceEventsResponse, error) {
+	if err := s.checkDeletesAllowed(); err != nil {
+		return nil, err
+	}
+	if req.Delete == nil {
+		return nil, twirp.RequiredArgumentError("delete")
+	}
+	if s.batchWriter.Paused() {
+		return nil, errPaused
+	}
+	entries, err := s.process(req.Entries)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.deleteIDs(req.Delete)
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[string]*pb.Event)
+	for _, entry := range entries {
+		if err := addEvents(events, entry, time.Time{}); err != nil {
 			return nil, err
 		}
-		if err := q.Exec(); err != nil {
+	}
+	if max := s.deletes.MaxReplaceRows; max > 0 && len(ids)+len(events) > max {
+		return nil, twirp.NewErrorf(twirp.FailedPrecondition,
+			"replacement removes %d and writes %d rows, more than %d", len(ids), len(events), max)
+	}
+
+	s.forgetRows(ids)
+	batch := s.session.NewBatch(gocql.LoggedBatch)
+	for _, id := range ids {
+		if err := s.removeQuery(batch, id); err != nil {
 			return nil, err
 		}
 	}
-	return &pb.DeleteEventsResponse{}, nil
+	if err := s.insertQueries(batch, events, flushOrderNone); err != nil {
+		return nil, err
+	}
+	if s.expiryNotifier != nil {
+		for _, entry := range entries {
+			s.expiryNotifier.Seen(entry.Origin)
+		}
+	}
+	log.Printf("Replacing %d records with %d records", len(ids), len(events))
+	if err := s.session.ExecuteBatch(batch); err != nil {
+		return nil, err
+	}
+	return &pb.ReplaceEventsResponse{}, nil
+}
+
+// hasEvents reports whether there are any events for the origin.
+const hasEventsStmt = `SELECT id FROM {{.Keyspace}}.events WHERE origin = ? LIMIT 1`
+
+func (s *Server) hasEvents(origin string) (bool, error) {
+	q, err := s.session.Query(hasEventsStmt, origin)
+	if err != nil {
+		return false, err
+	}
+	var id gocql.UUID
+	iter := q.Iter()
+	ok := iter.Scan(&id)
+	if err := iter.Close(); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// selectIDsStmt returns the statement selecting the ids
+// of the rows restricted by the WHERE clause.
+func (s *Server) selectIDsStmt(where string) string {
+	limit := ""
+	if max := s.deletes.MaxScan; max > 0 {
+		// Selecting one more row than the budget is
+		// enough to tell whether it is exceeded.
+		limit = fmt.Sprintf(" LIMIT %d", max+1)
+	}
+	return `SELECT id FROM {{.Keyspace}}.events ` + where + limit + ` ALLOW FILTERING`
+}
+
+// selectIDs returns the ids of the events matching the filter.
+func (s *Server) selectIDs(filter cassandra.Filter) ([]gocql.UUID, error) {
+	where, vals, err := filter.CQL()
+	if err != nil {
+		return nil, err
+	}
+	q, err := s.session.Query(s.selectIDsStmt(where), vals...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		id  gocql.UUID
+		ids []gocql.UUID
+	)
+	iter := q.Iter()
+	for iter.Scan(&id) {
+		ids = append(ids, id)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	if max := s.deletes.MaxScan; max > 0 && len(ids) > max {
+		return nil, twirp.NewErrorf(twirp.FailedPrecondition,
+			"filter matches more than %d rows, narrow it or delete by ids", max)
+	}
+	return ids, nil
+}
+
+func (s *Server) ListRecentEvents(ctx context.Context, req *pb.ListRecentEventsRequest) (*pb.ListRecentEventsResponse, error) {
+	recent := s.batchWriter.recent
+	if recent == nil {
+		return nil, twirp.NewError(twirp.FailedPrecondition, "recent events are not enabled")
+	}
+	return &pb.ListRecentEventsResponse{Entries: recent.List()}, nil
+}
+
+// PauseIngestion flushes the buffered events and rejects
+// all inserts until ResumeIngestion is called. Queries are
+// still served while ingestion is paused.
+func (s *Server) PauseIngestion(ctx context.Context, req *pb.PauseIngestionRequest) (*pb.PauseIngestionResponse, error) {
+	log.Printf("Pausing ingestion")
+	if err := s.batchWriter.Pause(); err != nil {
+		return nil, err
+	}
+	return &pb.PauseIngestionResponse{}, nil
+}
+
+func (s *Server) ResumeIngestion(ctx context.Context, req *pb.ResumeIngestionRequest) (*pb.ResumeIngestionResponse, error) {
+	log.Printf("Resuming ingestion")
+	s.batchWriter.Resume()
+	return &pb.ResumeIngestionResponse{}, nil
 }
 
-func newBatchWriter(server *Server, n int, flushInterval time.Duration) *batchWriter {
+// checkDeletesAllowed returns a PermissionDenied error if the
+// server is running in safe mode. Every code path that removes
+// data must call it first.
+func (s *Server) checkDeletesAllowed() error {
+	if s.safeMode {
+		return twirp.NewError(twirp.PermissionDenied, "deletes are disabled in safe mode")
+	}
+	return nil
+}
+
+func newBatchWriter(server *Server, cfg config.FlushConfig) *batchWriter {
 	// TODO: Implement an optional WAL.
-	return &batchWriter{
+	b := &batchWriter{
 		server:        server,
-		n:             n,
-		flushInterval: flushInterval,
-		events:        make(map[string]*pb.Event, n),
+		n:             cfg.BufferSize,
+		flushInterval: cfg.Interval,
+		minInterval:   cfg.MinInterval,
+		bucketSize:    cfg.BucketSize,
+		retries:       cfg.Retries,
+		retryBackoff:  cfg.RetryBackoff,
+		order:         cfg.Order,
+		events:        make(map[string]*pb.Event, cfg.BufferSize),
+		inFlight:      make(map[uint64]bool),
+	}
+	b.flushed = sync.NewCond(&b.mu)
+	if cfg.MergeWindow > 0 {
+		b.merger = newRowMerger(cfg.MergeWindow)
 	}
+	return b
 }
 
+const (
+	flushOrderNone    = ""
+	flushOrderKey     = "key"
+	flushOrderShuffle = "shuffle"
+)
+
+// orderKeys returns the keys of the events in the flush order.
+// Rows are partitioned by their random ids, only the index
+// updates of rows sharing attributes contend: "key" groups
+// them together, "shuffle" spreads them across the batch.
+func orderKeys(events map[string]*pb.Event, order string) []string {
+	keys := make([]string, 0, len(events))
+	for k := range events {
+		keys = append(keys, k)
+	}
+	switch order {
+	case flushOrderKey:
+		sort.Strings(keys)
+	case flushOrderShuffle:
+		rand.Shuffle(len(keys), func(i, j int) {
+			keys[i], keys[j] = keys[j], keys[i]
+		})
+	}
+	return keys
+}
+
+// errPaused is returned when writing while ingestion is paused.
+var errPaused = twirp.NewError(twirp.Unavailable, "ingestion is paused").
+	WithMeta("retry_after", "30s")
+
 type batchWriter struct {
 	mu         sync.Mutex
 	events     map[string]*pb.Event
 	lastExport time.Time
+	paused     bool
+
+	// Flushes release mu while writing, the ones in
+	// progress are in inFlight by their sequence number.
+	seq      uint64
+	inFlight map[uint64]bool
+	flushed  *sync.Cond // signaled when a flush finishes
 
 	n             int
 	flushInterval time.Duration
+	minInterval   time.Duration
+	bucketSize    time.Duration
+	retries       int
+	retryBackoff  time.Duration
+	order         string
 	server        *Server
+
+	recent *ringBuffer // nil if disabled
+	merger *rowMerger  // nil if disabled
 }
 
 func (b *batchWriter) Write(e *pb.Entry) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	for _, event := range e.Events {
-		key := key(e.Origin, e.TraceId, event.Name, event.Unit)
-		v, ok := b.events[key]
-		if !ok {
-			b.events[key] = event
-		} else {
-			v.Value += event.Value
-			b.events[key] = v
-		}
+	if b.paused {
+		return errPaused
+	}
+	if err := addEvents(b.events, e, b.bucket(time.Now())); err != nil {
+		return err
+	}
+	if b.recent != nil {
+		b.recent.Add(e)
 	}
 	return b.flushIfNeeded()
 }
 
+// WriteSync writes the entries to the datastore immediately
+// without buffering them. Already buffered events are not flushed.
+func (b *batchWriter) WriteSync(entries []*pb.Entry) error {
+	if b.Paused() {
+		return errPaused
+	}
+	events := make(map[string]*pb.Event)
+	for _, e := range entries {
+		if err := addEvents(events, e, b.bucket(time.Now())); err != nil {
+			return err
+		}
+		if b.recent != nil {
+			b.recent.Add(e)
+		}
+	}
+	return b.flush(events)
+}
+
 func (b *batchWriter) flushIfNeeded() error {
 	// flushIfNeeded need to be called from Write.
+	if time.Since(b.lastExport) < b.minInterval {
+		return nil
+	}
 	if len(b.events) > b.n || b.lastExport.Before(time.Now().Add(-1*b.flushInterval)) {
-		log.Printf("Batch writing %d records", len(b.events))
+		return b.flushBuffer()
+	}
+	return nil
+}
 
-		batch := b.server.session.NewBatch(gocql.UnloggedBatch)
-		for key, e := range b.events {
-			origin, traceID, name, unit := parseKey(key)
+// bucket returns the start of the time bucket of t,
+// or the zero time if events are not bucketed.
+func (b *batchWriter) bucket(t time.Time) time.Time {
+	if b.bucketSize <= 0 {
+		return time.Time{}
+	}
+	return t.Truncate(b.bucketSize)
+}
 
-			id, err := gocql.RandomUUID()
-			if err != nil {
-				return err
+// flushBuffer flushes all buffered events. It needs to be
+// called with b.mu held, it is released during the flush.
+func (b *batchWriter) flushBuffer() error {
+	events := b.events
+	b.events = make(map[string]*pb.Event, b.n)
+	b.lastExport = time.Now() // the writes during the flush don't flush again
+	if err := b.flushTaken(events); err != nil {
+		// Retry after the minimum interval, not with each write
+		// while the datastore is failing.
+		b.lastExport = time.Now()
+		return err
+	}
+	b.lastExport = time.Now()
+	return nil
+}
+
+// flushTaken flushes the events taken out of the buffer. It needs
+// to be called with b.mu held, it is released during the flush, so
+// writes don't wait for the datastore and its retries. If the flush
+// fails, the events not written are put back in the buffer.
+func (b *batchWriter) flushTaken(events map[string]*pb.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	seq := b.seq
+	b.seq++
+	b.inFlight[seq] = true
+	b.mu.Unlock()
+
+	err := b.flush(events)
+
+	b.mu.Lock()
+	delete(b.inFlight, seq)
+	b.flushed.Broadcast()
+	if err != nil {
+		b.restore(events)
+	}
+	return err
+}
+
+// restore puts the events of a failed flush back in the buffer,
+// adding them to the ones buffered since. It needs to be called
+// with b.mu held.
+func (b *batchWriter) restore(events map[string]*pb.Event) {
+	for k, e := range events {
+		if v, ok := b.events[k]; ok {
+			if err := addValue(e, v); err != nil {
+				log.Printf("Dropping the unwritten events of %q: %v", k, err)
+				continue
 			}
-			if err := batch.Query(`
-				INSERT INTO {{.Keyspace}}.events
-				(id, trace_id, origin, event, value, unit, created_at)
-				VALUES ( ?, ?, ?, ?, ?, ?, ? )
-				USING TTL {{.TTL}}`,
-				id.String(), traceID, origin, name, e.Value, unit, time.Now()); err != nil {
-				return err
+		}
+		b.events[k] = e
+	}
+}
+
+// waitFlushes waits for the flushes started so far to finish,
+// so the events they failed to write are back in the buffer.
+// It needs to be called with b.mu held.
+func (b *batchWriter) waitFlushes() {
+	seq := b.seq
+	for {
+		var waiting bool
+		for s := range b.inFlight {
+			if s < seq {
+				waiting = true
+				break
+			}
+		}
+		if !waiting {
+			return
+		}
+		b.flushed.Wait()
+	}
+}
+
+// Flush flushes all buffered events, including the
+// ones of the flushes in progress.
+func (b *batchWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.waitFlushes()
+	return b.flushBuffer()
+}
+
+// Pause flushes the buffered events and rejects
+// all writes until Resume is called.
+func (b *batchWriter) Pause() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.paused = true
+	b.waitFlushes()
+	return b.flushBuffer()
+}
+
+func (b *batchWriter) Resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.paused = false
+}
+
+// FlushMatching flushes the buffered events whose keys match
+// and keeps the others buffered. The flushes in progress are
+// waited for first, they may be writing matching events.
+func (b *batchWriter) FlushMatching(match func(key string) bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.waitFlushes()
+	events := make(map[string]*pb.Event)
+	for k, e := range b.events {
+		if match(k) {
+			events[k] = e
+			delete(b.events, k)
+		}
+	}
+	return b.flushTaken(events)
+}
+
+func (b *batchWriter) Paused() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.paused
+}
+
+// flush writes the events to the datastore. If it fails, the
+// events written before the failure are removed from events.
+func (b *batchWriter) flush(events map[string]*pb.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	log.Printf("Batch writing %d records", len(events))
+
+	batch := b.server.session.NewBatch(gocql.UnloggedBatch)
+	if b.merger != nil {
+		return b.merger.flush(b, batch, events)
+	}
+	if err := b.server.insertQueries(batch, events, b.order); err != nil {
+		return err
+	}
+	return b.execute(batch)
+}
+
+// execute executes the batch, retrying on transient errors.
+func (b *batchWriter) execute(batch datastore.Batch) error {
+	err := b.server.session.ExecuteBatch(batch)
+	for i := 0; i < b.retries && cassandra.IsRetryable(err); i++ {
+		backoff := b.retryBackoff << i
+		log.Printf("Batch write failed, retrying in %v: %v", backoff, err)
+		time.Sleep(backoff)
+		err = b.server.session.ExecuteBatch(batch)
+	}
+	// TODO: Drop the samples if retries fail.
+	return err
+}
+
+// mergeEvents adds the values and counts of src into dst.
+func mergeEvents(dst, src map[string]*pb.Event) error {
+	for k, e := range src {
+		v, ok := dst[k]
+		if !ok {
+			dst[k] = e
+			continue
+		}
+		if err := addValue(v, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subtractEvents subtracts the values of baseline from v.
+// Events missing on either side are considered to be zero.
+func subtractEvents(v, baseline map[string]*pb.Event) error {
+	for k, b := range baseline {
+		e, ok := v[k]
+		if !ok {
+			e = &pb.Event{Name: b.Name, Unit: b.Unit}
+			v[k] = e
+		}
+		neg := &pb.Event{Value: -b.Value}
+		if b.IntValue != nil {
+			if *b.IntValue == math.MinInt64 {
+				return errIntOverflow
 			}
+			n := -*b.IntValue
+			neg.IntValue = &n
 		}
-		if err := b.server.session.ExecuteBatch(batch); err != nil {
-			// TODO: Retry and drop the samples if retries fail.
+		if err := addValue(e, neg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertQueries adds an insert query for each event to the batch.
+func (s *Server) insertQueries(batch datastore.Batch, events map[string]*pb.Event, order string) error {
+	for _, key := range orderKeys(events, order) {
+		id, err := gocql.RandomUUID()
+		if err != nil {
+			return err
+		}
+		if err := s.insertQuery(batch, key, events[key], id, createdAt(key)); err != nil {
 			return err
 		}
-		b.events = make(map[string]*pb.Event, b.n)
-		b.lastExport = time.Now()
+	}
+	return nil
+}
+
+const insertStmt = `
+		INSERT INTO {{.Keyspace}}.events
+		(id, trace_id, origin, event, value, int_value, unit, created_at)
+		VALUES ( ?, ?, ?, ?, ?, ?, ?, ? )
+		USING TTL ?`
+
+// insertQuery adds a query writing the event to the row with the
+// given id. An existing row with the id is overwritten.
+func (s *Server) insertQuery(batch datastore.Batch, key string, e *pb.Event, id gocql.UUID, createdAt time.Time) error {
+	origin, traceID, name, unit, _ := parseKey(key)
+	return batch.Query(insertStmt,
+		id.String(), traceID, origin, name, e.Value, e.IntValue, unit, createdAt,
+		int64(s.eventTTLs.ttlOf(name)/time.Second))
+}
+
+// createdAt returns the creation time of a row written for
+// the key, the start of its bucket if it has one.
+func createdAt(key string) time.Time {
+	if _, _, _, _, bucket := parseKey(key); !bucket.IsZero() {
+		return bucket
+	}
+	return time.Now()
+}
+
+// addEvents adds the events of the entry to events. If an integer
+// value overflows, events is left unchanged.
+func addEvents(events map[string]*pb.Event, e *pb.Entry, bucket time.Time) error {
+	added := make(map[string]*pb.Event, len(e.Events))
+	for _, event := range e.Events {
+		key := key(e.Origin, e.TraceId, event.Name, event.Unit, bucket)
+		v, ok := added[key]
+		if !ok {
+			if v, ok = events[key]; ok {
+				v = proto.Clone(v).(*pb.Event)
+			}
+		}
+		if !ok {
+			added[key] = event
+			continue
+		}
+		if err := addValue(v, event); err != nil {
+			return err
+		}
+		added[key] = v
+	}
+	for k, v := range added {
+		events[k] = v
 	}
 	return nil
 }
@@ -208,18 +1546,12 @@ func (s *eventSorter) Len() int {
 }
 
 func (s *eventSorter) Less(i, j int) bool {
-	return s.events[i].Name < s.events[j].Name
+	if s.events[i].Name != s.events[j].Name {
+		return s.events[i].Name < s.events[j].Name
+	}
+	return s.events[i].Unit < s.events[j].Unit
 }
 
 func (s *eventSorter) Swap(i, j int) {
 	s.events[i], s.events[j] = s.events[j], s.events[i]
 }
-
-func key(origin, traceID, name, unit string) string {
-	return origin + ":" + traceID + ":" + name + ":" + unit
-}
-
-func parseKey(key string) (origin, traceID, name, unit string) {
-	v := strings.Split(key, ":")
-	return v[0], v[1], v[2], v[3]
-}
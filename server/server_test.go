@@ -0,0 +1,494 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// errorCode returns the twirp error code of err,
+// empty if it is nil or not a twirp error.
+func errorCode(err error) twirp.ErrorCode {
+	var twerr twirp.Error
+	if errors.As(err, &twerr) {
+		return twerr.Code()
+	}
+	return ""
+}
+
+func TestSafeMode(t *testing.T) {
+	id := gocql.MustRandomUUID()
+	tests := []struct {
+		name     string
+		safeMode bool
+		wantCode twirp.ErrorCode
+		wantRuns int // deletes run
+	}{
+		{name: "on", safeMode: true, wantCode: twirp.PermissionDenied},
+		{name: "off", wantRuns: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				return idRows(id), nil
+			})
+			cfg := testConfig()
+			cfg.SafeMode = tt.safeMode
+			s := newTestServer(t, cfg, session)
+
+			_, err := s.DeleteEvents(context.Background(), &pb.DeleteEventsRequest{Origin: "a"})
+			if code := errorCode(err); code != tt.wantCode || (code == "" && err != nil) {
+				t.Errorf("DeleteEvents() = %v, want code %q", err, tt.wantCode)
+			}
+			if n := len(session.ran("DELETE")); n != tt.wantRuns {
+				t.Errorf("DeleteEvents() ran %d deletes, want %d", n, tt.wantRuns)
+			}
+
+			_, err = s.ReplaceEvents(context.Background(), &pb.ReplaceEventsRequest{
+				Delete: &pb.DeleteEventsRequest{Origin: "a"},
+			})
+			if code := errorCode(err); code != tt.wantCode || (code == "" && err != nil) {
+				t.Errorf("ReplaceEvents() = %v, want code %q", err, tt.wantCode)
+			}
+			if n := len(session.executed("DELETE")); n != tt.wantRuns {
+				t.Errorf("ReplaceEvents() ran %d deletes, want %d", n, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    string
+		mergeWindow time.Duration
+		parallelism int
+		want        []string // prepared on both sessions
+	}{
+		{name: "default", want: []string{insertStmt, deleteStmt, hasEventsStmt}},
+		{
+			name:        "expire and merge",
+			strategy:    deleteExpire,
+			mergeWindow: time.Minute,
+			parallelism: 4,
+			want:        []string{insertStmt, expireSelectStmt, expireInsertStmt, mergeStmt},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, analytics := newFakeSession(), newFakeSession()
+			cfg := testConfig()
+			cfg.DeleteConfig.Strategy = tt.strategy
+			cfg.FlushConfig.MergeWindow = tt.mergeWindow
+			cfg.QueryConfig.Parallelism = tt.parallelism
+			s := newTestServer(t, cfg, nil, WithSessions(primary, analytics))
+			if err := s.prepare(); err != nil {
+				t.Fatalf("prepare() = %v", err)
+			}
+
+			for _, session := range []*fakeSession{primary, analytics} {
+				prepared := make(map[string]bool)
+				for _, stmt := range session.prepared {
+					prepared[stmt] = true
+				}
+				for _, stmt := range tt.want {
+					if !prepared[stmt] {
+						t.Errorf("prepare() didn't prepare %q", stmt)
+					}
+				}
+			}
+
+			// The scans of common queries run prepared statements.
+			s.Query(context.Background(), &pb.QueryRequest{Origin: "a"})
+			s.Query(context.Background(), &pb.QueryRequest{
+				TraceId:   "t",
+				StartTime: timestamppb.New(time.Unix(10, 0)),
+				EndTime:   timestamppb.New(time.Unix(20, 0)),
+			})
+			scans := analytics.ran("SELECT")
+			if len(scans) == 0 {
+				t.Fatal("Query() ran no scans")
+			}
+			for _, q := range scans {
+				if !contains(analytics.prepared, q.stmt) {
+					t.Errorf("Query() ran %q, not prepared", q.stmt)
+				}
+			}
+		})
+	}
+}
+
+func contains(stmts []string, stmt string) bool {
+	for _, s := range stmts {
+		if s == stmt {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWarmUpReady(t *testing.T) {
+	session := newFakeSession()
+	release := make(chan struct{})
+	session.prepareWait = release
+	cfg := testConfig()
+	cfg.DataConfig.CassandraConfig.WarmUp = true
+	s := newTestServer(t, cfg, session)
+
+	if s.Ready() {
+		t.Fatal("Ready() = true while warming up")
+	}
+	close(release)
+	for deadline := time.Now().Add(time.Second); !s.Ready(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Ready() = false after warming up")
+		}
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if !contains(session.prepared, insertStmt) {
+		t.Errorf("warm up didn't prepare %q", insertStmt)
+	}
+}
+
+func TestFlushReleasesBuffer(t *testing.T) {
+	session := newFakeSession()
+	blocked, release := make(chan struct{}), make(chan error)
+	var batches int32
+	session.batchErr = func(b *fakeBatch) error {
+		if atomic.AddInt32(&batches, 1) == 1 {
+			close(blocked)
+			return <-release
+		}
+		return nil
+	}
+	s := newTestServer(t, testConfig(), session)
+	b := s.batchWriter
+	write := func() error {
+		return b.Write(&pb.Entry{Origin: "o", Events: []*pb.Event{{Name: "a", Value: 1, Count: 1}}})
+	}
+
+	flushed := make(chan error)
+	go func() { flushed <- write() }() // flushes, the buffer is empty
+	<-blocked
+
+	written := make(chan error)
+	go func() { written <- write() }()
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() waited for the flush in progress")
+	}
+
+	matched := make(chan error)
+	go func() { matched <- b.FlushMatching(func(string) bool { return true }) }()
+	select {
+	case <-matched:
+		t.Fatal("FlushMatching() didn't wait for the flush in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release <- errors.New("failed")
+	if err := <-flushed; err == nil {
+		t.Error("Write() = nil, want the flush error")
+	}
+	if err := <-matched; err != nil {
+		t.Fatalf("FlushMatching() = %v", err)
+	}
+	// The failed event is put back and flushed with the new one.
+	inserts := session.executed("INSERT")
+	if len(inserts) != 1 || inserts[0].vals[4] != 2.0 {
+		t.Errorf("FlushMatching() inserted %v, want one row of value 2", inserts)
+	}
+}
+
+func TestMinFlushInterval(t *testing.T) {
+	const minInterval = 20 * time.Millisecond
+	tests := []struct {
+		name string
+		err  error // of each flush
+	}{
+		{name: "succeeding"},
+		{name: "failing", err: errors.New("write failed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			var flushes []time.Time
+			session.batchErr = func(*fakeBatch) error {
+				flushes = append(flushes, time.Now()) // flushes of Write are sequential
+				return tt.err
+			}
+			cfg := testConfig()
+			cfg.FlushConfig.BufferSize = 1
+			cfg.FlushConfig.MinInterval = minInterval
+			s := newTestServer(t, cfg, session)
+
+			for start := time.Now(); time.Since(start) < 10*minInterval; {
+				err := s.batchWriter.Write(&pb.Entry{Origin: "o", Events: []*pb.Event{
+					{Name: fmt.Sprint(time.Now().UnixNano()), Value: 1},
+				}})
+				if err != nil && tt.err == nil {
+					t.Fatalf("Write() = %v", err)
+				}
+			}
+			if len(flushes) < 2 {
+				t.Fatalf("Write() flushed %d times, want the full buffer flushed repeatedly", len(flushes))
+			}
+			for i := 1; i < len(flushes); i++ {
+				if d := flushes[i].Sub(flushes[i-1]); d < minInterval {
+					t.Errorf("flush %d ran %v after the previous one, want at least %v", i, d, minInterval)
+				}
+			}
+		})
+	}
+}
+
+func TestBucketedFlush(t *testing.T) {
+	session := newFakeSession()
+	cfg := testConfig()
+	cfg.FlushConfig.BucketSize = time.Minute
+	s := newTestServer(t, cfg, session)
+	b := s.batchWriter
+
+	first := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+	second := first.Add(time.Minute)
+	for _, at := range []time.Time{first, first.Add(10 * time.Second), second} {
+		entry := &pb.Entry{Origin: "o", Events: []*pb.Event{{Name: "e", Value: 1, Count: 1}}}
+		if err := addEvents(b.events, entry, b.bucket(at)); err != nil {
+			t.Fatalf("addEvents() = %v", err)
+		}
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+
+	got := make(map[int64]float64) // created_at -> value
+	inserts := session.executed("INSERT")
+	for _, st := range inserts {
+		got[st.vals[7].(time.Time).Unix()] += st.vals[4].(float64)
+	}
+	want := map[int64]float64{
+		first.Truncate(time.Minute).Unix():  2,
+		second.Truncate(time.Minute).Unix(): 1,
+	}
+	if len(inserts) != 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("Flush() wrote %d rows of values by created_at %v, want 2 rows of %v", len(inserts), got, want)
+	}
+}
+
+func TestFlushOrder(t *testing.T) {
+	entries := []*pb.Entry{
+		{Origin: "b", TraceId: "t", Events: []*pb.Event{{Name: "y", Value: 1}, {Name: "x", Value: 2}}},
+		{Origin: "a", TraceId: "u", Events: []*pb.Event{{Name: "z", Value: 3}}},
+		{Origin: "a", TraceId: "t", Events: []*pb.Event{{Name: "y", Value: 4}, {Name: "w", Value: 5}}},
+		{Origin: "c", Events: []*pb.Event{{Name: "v", Value: 6}}},
+	}
+	sorted := []string{"a/t/w", "a/t/y", "a/u/z", "b/t/x", "b/t/y", "c//v"}
+	tests := []struct {
+		order  string
+		sorted bool
+	}{
+		{order: flushOrderKey, sorted: true},
+		{order: flushOrderShuffle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			session := newFakeSession()
+			cfg := testConfig()
+			cfg.FlushConfig.Order = tt.order
+			s := newTestServer(t, cfg, session)
+			if err := s.batchWriter.WriteSync(entries); err != nil {
+				t.Fatalf("WriteSync() = %v", err)
+			}
+
+			var got []string // of origin/trace/event
+			for _, st := range session.executed("INSERT") {
+				got = append(got, fmt.Sprintf("%s/%s/%s", st.vals[2], st.vals[1], st.vals[3]))
+			}
+			if tt.sorted {
+				if !reflect.DeepEqual(got, sorted) {
+					t.Errorf("flush wrote %q, want %q", got, sorted)
+				}
+				return
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, sorted) {
+				t.Errorf("flush wrote %q in any order, want %q", got, sorted)
+			}
+		})
+	}
+}
+
+func TestSessionRouting(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(s *Server) error
+		wantPrimary bool
+	}{
+		{
+			name: "query",
+			run: func(s *Server) error {
+				_, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o"})
+				return err
+			},
+		},
+		{
+			name: "trace",
+			run: func(s *Server) error {
+				_, err := s.QueryTrace(context.Background(), &pb.QueryTraceRequest{TraceId: "t"})
+				return err
+			},
+		},
+		{
+			name: "sync insert",
+			run: func(s *Server) error {
+				ctx := context.WithValue(context.Background(), writeModeKey{}, writeModeSync)
+				_, err := s.InsertEvents(ctx, &pb.InsertEventsRequest{Entries: []*pb.Entry{{
+					Origin: "o",
+					Events: []*pb.Event{{Name: "e", Value: 1}},
+				}}})
+				return err
+			},
+			wantPrimary: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, analytics := newFakeSession(), newFakeSession()
+			analytics.consistency = gocql.LocalOne
+			s := newTestServer(t, testConfig(), nil, WithSessions(primary, analytics))
+
+			if err := tt.run(s); err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			used, other := analytics, primary
+			if tt.wantPrimary {
+				used, other = primary, analytics
+			}
+			if n := len(other.ran("")) + len(other.executed("")); n > 0 {
+				t.Errorf("run() ran %d statements with the other session", n)
+			}
+			if tt.wantPrimary {
+				if inserts := used.executed("INSERT"); len(inserts) != 1 {
+					t.Errorf("run() inserted %d rows with the primary session, want 1", len(inserts))
+				}
+				return
+			}
+			scans := used.ran("SELECT")
+			if len(scans) == 0 {
+				t.Fatal("run() ran no scans with the analytics session")
+			}
+			for _, q := range scans {
+				if q.consistency != gocql.LocalOne {
+					t.Errorf("run() scanned at %v, want %v", q.consistency, gocql.LocalOne)
+				}
+			}
+		})
+	}
+}
+
+func TestFlushRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int // before the batch is written
+		err          error
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "write timeout", failures: 2, err: requestError(gocql.ErrCodeWriteTimeout), wantAttempts: 3},
+		{name: "too many timeouts", failures: 5, err: requestError(gocql.ErrCodeWriteTimeout), wantErr: true, wantAttempts: 4},
+		{name: "syntax", failures: 1, err: requestError(gocql.ErrCodeSyntax), wantErr: true, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			var attempts int
+			session.batchErr = func(*fakeBatch) error {
+				attempts++
+				if attempts <= tt.failures {
+					return tt.err
+				}
+				return nil
+			}
+			cfg := testConfig()
+			cfg.FlushConfig.Retries = 3
+			cfg.FlushConfig.RetryBackoff = time.Millisecond
+			s := newTestServer(t, cfg, session)
+
+			// The first write flushes, nothing was flushed before.
+			err := s.batchWriter.Write(&pb.Entry{Origin: "o", Events: []*pb.Event{{Name: "e", Value: 1}}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Write() = %v, want error %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("Write() executed the batch %d times, want %d", attempts, tt.wantAttempts)
+			}
+			if n := len(session.executed("INSERT")); n != 1 && !tt.wantErr {
+				t.Errorf("Write() inserted %d rows, want 1", n)
+			}
+		})
+	}
+}
+
+func TestPauseIngestion(t *testing.T) {
+	session := newFakeSession()
+	s := newTestServer(t, testConfig(), session)
+	defer s.Close()
+	s.batchWriter.lastExport = time.Now() // nothing is due to be flushed
+
+	insert := func(mode writeMode) error {
+		ctx := context.WithValue(context.Background(), writeModeKey{}, mode)
+		_, err := s.InsertEvents(ctx, &pb.InsertEventsRequest{Entries: []*pb.Entry{{
+			Origin: "o",
+			Events: []*pb.Event{{Name: "e", Value: 1}},
+		}}})
+		return err
+	}
+	modes := map[string]writeMode{"buffered": writeModeBuffered, "sync": writeModeSync, "async": writeModeAsync}
+
+	if err := insert(writeModeBuffered); err != nil {
+		t.Fatalf("InsertEvents() = %v", err)
+	}
+	if _, err := s.PauseIngestion(context.Background(), &pb.PauseIngestionRequest{}); err != nil {
+		t.Fatalf("PauseIngestion() = %v", err)
+	}
+	if n := len(session.executed("INSERT")); n != 1 {
+		t.Errorf("PauseIngestion() flushed %d rows, want 1", n)
+	}
+
+	for name, mode := range modes {
+		err := insert(mode)
+		var twerr twirp.Error
+		if !errors.As(err, &twerr) || twerr.Code() != twirp.Unavailable || twerr.Meta("retry_after") == "" {
+			t.Errorf("%s InsertEvents() while paused = %v, want Unavailable with retry_after", name, err)
+		}
+	}
+	if _, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o"}); err != nil {
+		t.Errorf("Query() while paused = %v", err)
+	}
+
+	if _, err := s.ResumeIngestion(context.Background(), &pb.ResumeIngestionRequest{}); err != nil {
+		t.Fatalf("ResumeIngestion() = %v", err)
+	}
+	for name, mode := range modes {
+		if err := insert(mode); err != nil {
+			t.Errorf("%s InsertEvents() after resuming = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/mykodev/myko/config"
+	"github.com/mykodev/myko/datastore"
+)
+
+// fakeSession is an in-memory datastore.Session. Statements are
+// kept as templates. Queries are answered by the handler, which
+// returns the rows of the query or an error.
+type fakeSession struct {
+	*fakeDB
+	keyspace string
+}
+
+type fakeDB struct {
+	mu          sync.Mutex
+	consistency gocql.Consistency
+	handler     func(q *fakeQuery) ([][]interface{}, error)
+	batchErr    func(b *fakeBatch) error
+	schemaErr   error
+	prepareWait chan struct{} // Prepare waits for it if non-nil
+
+	queries  []*fakeQuery // in the order they were run
+	batches  []*fakeBatch // in the order they were executed
+	prepared []string
+}
+
+func newFakeSession() *fakeSession {
+	return &fakeSession{
+		fakeDB:   &fakeDB{consistency: gocql.Quorum},
+		keyspace: "myko",
+	}
+}
+
+// handle sets the handler answering the queries.
+func (s *fakeSession) handle(h func(q *fakeQuery) ([][]interface{}, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handler = h
+}
+
+func (s *fakeSession) Query(stmt string, vals ...interface{}) (datastore.Query, error) {
+	return &fakeQuery{
+		session:     s,
+		keyspace:    s.keyspace,
+		stmt:        stmt,
+		vals:        vals,
+		consistency: s.consistency,
+	}, nil
+}
+
+func (s *fakeSession) Prepare(stmt string) error {
+	s.mu.Lock()
+	wait := s.prepareWait
+	s.mu.Unlock()
+	if wait != nil {
+		<-wait
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prepared = append(s.prepared, stmt)
+	return nil
+}
+
+func (s *fakeSession) NewBatch(typ gocql.BatchType) datastore.Batch {
+	return &fakeBatch{typ: typ, keyspace: s.keyspace}
+}
+
+func (s *fakeSession) ExecuteBatch(b datastore.Batch) error {
+	batch := b.(*fakeBatch)
+	s.mu.Lock()
+	batchErr := s.batchErr
+	s.mu.Unlock()
+	if batchErr != nil {
+		if err := batchErr(batch); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *fakeSession) InKeyspace(keyspace string) datastore.Session {
+	return &fakeSession{fakeDB: s.fakeDB, keyspace: keyspace}
+}
+
+func (s *fakeSession) Consistency() gocql.Consistency {
+	return s.consistency
+}
+
+func (s *fakeSession) CheckSchema() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.schemaErr
+}
+
+// run records the query and answers it with the handler.
+func (s *fakeSession) run(q *fakeQuery) ([][]interface{}, error) {
+	s.mu.Lock()
+	s.queries = append(s.queries, q)
+	h := s.handler
+	s.mu.Unlock()
+	if h == nil {
+		return nil, nil
+	}
+	return h(q)
+}
+
+// ran returns the queries run so far whose statements contain substr.
+func (s *fakeSession) ran(substr string) []*fakeQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var queries []*fakeQuery
+	for _, q := range s.queries {
+		if strings.Contains(q.stmt, substr) {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// executed returns the statements of the batches executed so far
+// that contain substr.
+func (s *fakeSession) executed(substr string) []fakeStatement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stmts []fakeStatement
+	for _, b := range s.batches {
+		for _, st := range b.stmts {
+			if strings.Contains(st.stmt, substr) {
+				stmts = append(stmts, st)
+			}
+		}
+	}
+	return stmts
+}
+
+type fakeQuery struct {
+	session     *fakeSession
+	keyspace    string
+	stmt        string
+	vals        []interface{}
+	consistency gocql.Consistency
+	ctx         context.Context
+}
+
+func (q *fakeQuery) Consistency(c gocql.Consistency) datastore.Query {
+	q.consistency = c
+	return q
+}
+
+func (q *fakeQuery) WithContext(ctx context.Context) datastore.Query {
+	q.ctx = ctx
+	return q
+}
+
+func (q *fakeQuery) Exec() error {
+	_, err := q.session.run(q)
+	return err
+}
+
+func (q *fakeQuery) Scan(dest ...interface{}) error {
+	rows, err := q.session.run(q)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return gocql.ErrNotFound
+	}
+	return assign(dest, rows[0])
+}
+
+func (q *fakeQuery) ScanCAS(dest ...interface{}) (bool, error) {
+	rows, err := q.session.run(q)
+	if err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
+}
+
+func (q *fakeQuery) Iter() datastore.Iter {
+	rows, err := q.session.run(q)
+	return &fakeIter{rows: rows, err: err}
+}
+
+type fakeIter struct {
+	rows [][]interface{}
+	err  error
+}
+
+func (it *fakeIter) Scan(dest ...interface{}) bool {
+	if it.err != nil || len(it.rows) == 0 {
+		return false
+	}
+	row := it.rows[0]
+	it.rows = it.rows[1:]
+	if err := assign(dest, row); err != nil {
+		it.err = err
+		return false
+	}
+	return true
+}
+
+func (it *fakeIter) Close() error {
+	return it.err
+}
+
+// assign sets the destinations to the values of the row,
+// nil values set them to their zero value.
+func assign(dest []interface{}, row []interface{}) error {
+	if len(dest) != len(row) {
+		return fmt.Errorf("scanning %d columns into %d values", len(row), len(dest))
+	}
+	for i, d := range dest {
+		v := reflect.ValueOf(d).Elem()
+		if row[i] == nil {
+			v.Set(reflect.Zero(v.Type()))
+			continue
+		}
+		rv := reflect.ValueOf(row[i])
+		if !rv.Type().AssignableTo(v.Type()) {
+			return fmt.Errorf("can't scan %T into %v", row[i], v.Type())
+		}
+		v.Set(rv)
+	}
+	return nil
+}
+
+type fakeBatch struct {
+	typ      gocql.BatchType
+	keyspace string
+	stmts    []fakeStatement
+}
+
+type fakeStatement struct {
+	stmt string
+	vals []interface{}
+}
+
+func (b *fakeBatch) Query(stmt string, vals ...interface{}) error {
+	b.stmts = append(b.stmts, fakeStatement{stmt: stmt, vals: vals})
+	return nil
+}
+
+func (b *fakeBatch) Size() int {
+	return len(b.stmts)
+}
+
+// testConfig returns the default config with nothing
+// running in the background.
+func testConfig() config.Config {
+	return config.DefaultConfig()
+}
+
+// newTestServer returns a server backed by the session,
+// or by the sessions given with the options if it is nil.
+func newTestServer(t *testing.T, cfg config.Config, session *fakeSession, opts ...Option) *Server {
+	t.Helper()
+	if session != nil {
+		opts = append([]Option{WithSessions(session, nil)}, opts...)
+	}
+	s, err := New(cfg, opts...)
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	return s
+}
+
+// idRows returns the rows of an id column.
+func idRows(ids ...gocql.UUID) [][]interface{} {
+	rows := make([][]interface{}, len(ids))
+	for i, id := range ids {
+		rows[i] = []interface{}{id}
+	}
+	return rows
+}
+
+// int64p returns a pointer to v.
+func int64p(v int64) *int64 {
+	return &v
+}
+
+// float64p returns a pointer to v.
+func float64p(v float64) *float64 {
+	return &v
+}
+
+// timeVals returns the times bound to the query.
+func timeVals(q *fakeQuery) []time.Time {
+	var times []time.Time
+	for _, v := range q.vals {
+		if t, ok := v.(time.Time); ok {
+			times = append(times, t)
+		}
+	}
+	return times
+}
+
+// unavailableError is the error returned if there are not
+// enough replicas for the consistency level of the query.
+type unavailableError struct{}
+
+func (unavailableError) Code() int       { return gocql.ErrCodeUnavailable }
+func (unavailableError) Message() string { return "not enough replicas" }
+func (unavailableError) Error() string   { return "not enough replicas" }
+
+// requestError is an error returned by the datastore with the code.
+type requestError int
+
+func (e requestError) Code() int       { return int(e) }
+func (e requestError) Message() string { return fmt.Sprintf("error %#x", int(e)) }
+func (e requestError) Error() string   { return e.Message() }
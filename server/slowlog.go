@@ -0,0 +1,30 @@
+package server
+
+import (
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/mykodev/myko/datastore/cassandra"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// slowQueryLog logs a sample of the queries slower than
+// the threshold. The zero value logs nothing.
+type slowQueryLog struct {
+	threshold  time.Duration
+	sampleRate float64
+}
+
+func (l slowQueryLog) log(filter cassandra.Filter, stats *pb.QueryStats, latency time.Duration) {
+	if l.threshold <= 0 || latency < l.threshold {
+		return
+	}
+	if l.sampleRate > 0 && rand.Float64() >= l.sampleRate {
+		return
+	}
+	log.Printf("Slow query (%v): trace_id=%q origin=%q event=%q start=%v end=%v rows_scanned=%d groups_returned=%d allow_filtering=%v",
+		latency, filter.TraceID, filter.Origin, filter.Event, filter.Start, filter.End,
+		stats.RowsScanned, stats.GroupsReturned, stats.AllowFiltering)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mykodev/myko/datastore/cassandra"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// captureLog returns the buffer the log is written to until
+// the end of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	w := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(w) })
+	return &buf
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    time.Duration // of the scans
+		wantLogs int
+	}{
+		{name: "fast"},
+		{name: "slow", delay: 20 * time.Millisecond, wantLogs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				time.Sleep(tt.delay)
+				return [][]interface{}{{"", "e", 1.0, nil, ""}}, nil
+			})
+			cfg := testConfig()
+			cfg.QueryConfig.SlowQueryThreshold = 10 * time.Millisecond
+			s := newTestServer(t, cfg, session)
+			buf := captureLog(t)
+
+			if _, err := s.Query(context.Background(), &pb.QueryRequest{Origin: "o"}); err != nil {
+				t.Fatalf("Query() = %v", err)
+			}
+			logs := strings.Count(buf.String(), "Slow query")
+			if logs != tt.wantLogs {
+				t.Fatalf("Query() logged %d slow queries, want %d: %s", logs, tt.wantLogs, buf)
+			}
+			if logs > 0 && !strings.Contains(buf.String(), `origin="o"`) {
+				t.Errorf("slow query log %q doesn't hold the filter", buf)
+			}
+		})
+	}
+}
+
+func TestSlowQueryLogSampleRate(t *testing.T) {
+	const n = 1000
+	tests := []struct {
+		name       string
+		sampleRate float64
+		min, max   int // logged out of n
+	}{
+		{name: "all", min: n, max: n},
+		{name: "half", sampleRate: 0.5, min: n / 4, max: 3 * n / 4},
+		{name: "none", sampleRate: 1e-12, max: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slowQueryLog{threshold: time.Second, sampleRate: tt.sampleRate}
+			buf := captureLog(t)
+			for i := 0; i < n; i++ {
+				l.log(cassandra.Filter{Origin: "o"}, &pb.QueryStats{}, 2*time.Second)
+			}
+			if logs := strings.Count(buf.String(), "Slow query"); logs < tt.min || logs > tt.max {
+				t.Errorf("logged %d of %d slow queries, want [%d, %d]", logs, n, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// bufferSnapshot is the file format of a snapshot
+// of the buffered events.
+type bufferSnapshot struct {
+	Time          time.Time                  `json:"time"`
+	LastExport    time.Time                  `json:"last_export"`
+	Paused        bool                       `json:"paused"`
+	BufferSize    int                        `json:"buffer_size"`
+	FlushInterval string                     `json:"flush_interval"`
+	MinInterval   string                     `json:"min_interval"`
+	Events        map[string]json.RawMessage `json:"events"`
+}
+
+// SnapshotBuffer writes the buffered events that are not
+// flushed yet to a file in the snapshot directory. The
+// buffer is not flushed.
+func (s *Server) SnapshotBuffer(ctx context.Context, req *pb.SnapshotBufferRequest) (*pb.SnapshotBufferResponse, error) {
+	if s.snapshotDir == "" {
+		return nil, twirp.NewError(twirp.FailedPrecondition, "snapshots are not enabled")
+	}
+	snapshot, err := s.batchWriter.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(s.snapshotDir, fmt.Sprintf("buffer-%d.json", snapshot.Time.UnixNano()))
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		return nil, err
+	}
+	log.Printf("Wrote %d buffered events to %q", len(snapshot.Events), path)
+	return &pb.SnapshotBufferResponse{
+		Path:   path,
+		Events: int64(len(snapshot.Events)),
+	}, nil
+}
+
+// Snapshot returns the buffered events and the state of the
+// writer. Events are copied under the lock and encoded after
+// it is released.
+func (b *batchWriter) Snapshot() (*bufferSnapshot, error) {
+	b.mu.Lock()
+	events := make(map[string]*pb.Event, len(b.events))
+	for k, e := range b.events {
+		events[k] = proto.Clone(e).(*pb.Event)
+	}
+	snapshot := &bufferSnapshot{
+		Time:          time.Now(),
+		LastExport:    b.lastExport,
+		Paused:        b.paused,
+		BufferSize:    b.n,
+		FlushInterval: b.flushInterval.String(),
+		MinInterval:   b.minInterval.String(),
+		Events:        make(map[string]json.RawMessage, len(events)),
+	}
+	b.mu.Unlock()
+
+	for k, e := range events {
+		buf, err := protojson.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Events[k] = buf
+	}
+	return snapshot, nil
+}
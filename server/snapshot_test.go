@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestSnapshotBuffer(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig()
+	cfg.DebugConfig.SnapshotDir = dir
+	cfg.FlushConfig.BufferSize = 100
+	cfg.FlushConfig.Interval = time.Minute
+	cfg.FlushConfig.MinInterval = time.Second
+	session := newFakeSession()
+	s := newTestServer(t, cfg, session)
+	lastExport := time.Now()
+	s.batchWriter.lastExport = lastExport // nothing is due to be flushed
+
+	for _, e := range []*pb.Entry{
+		{Origin: "o", Events: []*pb.Event{{Name: "a", Value: 1}, {Name: "b", Value: 2, Unit: "ms"}}},
+		{Origin: "o", Events: []*pb.Event{{Name: "a", Value: 3}}},
+	} {
+		if err := s.batchWriter.Write(e); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+	}
+	want := make(map[string]*pb.Event)
+	for k, e := range s.batchWriter.events {
+		want[k] = proto.Clone(e).(*pb.Event)
+	}
+
+	resp, err := s.SnapshotBuffer(context.Background(), &pb.SnapshotBufferRequest{})
+	if err != nil {
+		t.Fatalf("SnapshotBuffer() = %v", err)
+	}
+	if filepath.Dir(resp.Path) != dir {
+		t.Errorf("SnapshotBuffer() path = %q, want it in %q", resp.Path, dir)
+	}
+	if resp.Events != int64(len(want)) {
+		t.Errorf("SnapshotBuffer() events = %d, want %d", resp.Events, len(want))
+	}
+
+	buf, err := os.ReadFile(resp.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot bufferSnapshot
+	if err := json.Unmarshal(buf, &snapshot); err != nil {
+		t.Fatalf("snapshot is not valid JSON: %v", err)
+	}
+	if !snapshot.LastExport.Equal(lastExport) {
+		t.Errorf("snapshot last export = %v, want %v", snapshot.LastExport, lastExport)
+	}
+	if snapshot.Paused {
+		t.Error("snapshot is paused, want not paused")
+	}
+	if snapshot.BufferSize != 100 || snapshot.FlushInterval != "1m0s" || snapshot.MinInterval != "1s" {
+		t.Errorf("snapshot config = %d, %q, %q, want 100, %q, %q", snapshot.BufferSize, snapshot.FlushInterval, snapshot.MinInterval, "1m0s", "1s")
+	}
+	if len(snapshot.Events) != len(want) {
+		t.Fatalf("snapshot has %d events, want %d", len(snapshot.Events), len(want))
+	}
+	for k, e := range want {
+		raw, ok := snapshot.Events[k]
+		if !ok {
+			t.Errorf("snapshot is missing %q", k)
+			continue
+		}
+		var got pb.Event
+		if err := protojson.Unmarshal(raw, &got); err != nil {
+			t.Fatalf("snapshot event %q: %v", k, err)
+		}
+		if !proto.Equal(&got, e) {
+			t.Errorf("snapshot event %q = %v, want %v", k, &got, e)
+		}
+	}
+
+	if len(s.batchWriter.events) != len(want) {
+		t.Errorf("buffer has %d events after the snapshot, want %d", len(s.batchWriter.events), len(want))
+	}
+	if stmts := session.executed("INSERT"); len(stmts) != 0 {
+		t.Errorf("SnapshotBuffer() flushed %d events, want none", len(stmts))
+	}
+}
+
+func TestSnapshotBufferDisabled(t *testing.T) {
+	s := newTestServer(t, testConfig(), newFakeSession())
+	_, err := s.SnapshotBuffer(context.Background(), &pb.SnapshotBufferRequest{})
+	if code := errorCode(err); code != twirp.FailedPrecondition {
+		t.Errorf("SnapshotBuffer() error code = %q, want %q", code, twirp.FailedPrecondition)
+	}
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// maxFrameSize is the uppermost size of a single frame
+// in the binary ingestion protocol.
+const maxFrameSize = 4 << 20
+
+// ServeTCP accepts connections on l and inserts the entries
+// sent with the binary ingestion protocol. Each entry is sent as
+// a frame; a 4-byte big-endian length followed by the protobuf
+// encoded Entry. Entries are inserted the same way as InsertEvents,
+// sharing its request limit.
+//
+// The server replies to each frame with a frame of the same
+// format, empty if the entry is inserted or holding the error as
+// JSON like {"code": "...", "msg": "..."}, the body of a Twirp
+// error. If tenants are enabled, the first frame of a connection
+// holds the value of the tenant header instead of an entry.
+//
+// If maxConns is positive, connections above maxConns are closed
+// after a reply frame holding a ResourceExhausted error. If
+// idleTimeout is positive, connections are closed if no frame
+// arrives within it.
+func (s *Server) ServeTCP(l net.Listener, maxConns int, idleTimeout time.Duration) error {
+	var (
+		active  int64
+		backoff time.Duration
+	)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() {
+				// e.g. running out of file descriptors
+				if backoff == 0 {
+					backoff = 5 * time.Millisecond
+				} else if backoff *= 2; backoff > time.Second {
+					backoff = time.Second
+				}
+				log.Printf("Failed to accept a connection, retrying in %v: %v", backoff, err)
+				time.Sleep(backoff)
+				continue
+			}
+			return err
+		}
+		backoff = 0
+		if n := atomic.AddInt64(&active, 1); maxConns > 0 && n > int64(maxConns) {
+			atomic.AddInt64(&active, -1)
+			log.Printf("Rejecting connection from %v, too many connections", conn.RemoteAddr())
+			s.metrics.rejectedConns.Add(1)
+			go func() {
+				// Don't hold up accepting if the client doesn't read.
+				conn.SetWriteDeadline(time.Now().Add(time.Second))
+				writeReply(conn, errTooManyConns)
+				conn.Close()
+			}()
+			continue
+		}
+		s.metrics.activeConns.Add(1)
+		go func() {
+			defer atomic.AddInt64(&active, -1)
+			defer s.metrics.activeConns.Add(-1)
+			s.serveConn(conn, idleTimeout)
+		}()
+	}
+}
+
+var errTooManyConns = twirp.NewError(twirp.ResourceExhausted, "too many connections")
+
+func (s *Server) serveConn(conn net.Conn, idleTimeout time.Duration) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	read := func() ([]byte, error) {
+		if idleTimeout > 0 {
+			conn.SetDeadline(time.Now().Add(idleTimeout))
+		}
+		return readFrame(r)
+	}
+	reply := func(err error) bool {
+		if err := writeReply(conn, err); err != nil {
+			log.Printf("Failed to reply to %v: %v", conn.RemoteAddr(), err)
+			return false
+		}
+		return true
+	}
+
+	ctx := context.Background()
+	if s.tenants != nil {
+		buf, err := read()
+		if err != nil {
+			log.Printf("Failed to read tenant frame from %v: %v", conn.RemoteAddr(), err)
+			return
+		}
+		tenant := s.tenants(string(buf))
+		if tenant == "" && s.tenantRequired {
+			reply(errNoTenant)
+			return
+		}
+		if tenant != "" {
+			ctx = withTenant(ctx, tenant)
+		}
+		if !reply(nil) {
+			return
+		}
+	}
+	for {
+		buf, err := read()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to read frame from %v: %v", conn.RemoteAddr(), err)
+			return
+		}
+		var entry pb.Entry
+		if err := proto.Unmarshal(buf, &entry); err != nil {
+			if !reply(twirp.NewError(twirp.Malformed, err.Error())) {
+				return
+			}
+			continue
+		}
+		if !reply(s.insertFrame(ctx, &entry)) {
+			return
+		}
+	}
+}
+
+// insertFrame inserts the entry of a frame within the request limit.
+func (s *Server) insertFrame(ctx context.Context, entry *pb.Entry) error {
+	if s.limiter != nil {
+		if err := s.limiter.acquire(ctx); err != nil {
+			return err
+		}
+		defer s.limiter.release()
+	}
+	_, err := s.InsertEvents(ctx, &pb.InsertEventsRequest{
+		Entries: []*pb.Entry{entry},
+	})
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(header[:])
+	if n > maxFrameSize {
+		return nil, errors.New("frame is too large")
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// writeReply writes the reply frame of err.
+func writeReply(w io.Writer, err error) error {
+	var body []byte
+	if err != nil {
+		var twerr twirp.Error
+		if !errors.As(err, &twerr) {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		body, err = json.Marshal(struct {
+			Code string `json:"code"`
+			Msg  string `json:"msg"`
+		}{Code: string(twerr.Code()), Msg: twerr.Msg()})
+		if err != nil {
+			return err
+		}
+	}
+	buf := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(len(body)))
+	copy(buf[4:], body)
+	_, err = w.Write(buf)
+	return err
+}
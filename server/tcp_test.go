@@ -0,0 +1,214 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mykodev/myko/config"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// writeFrame writes a frame of the binary ingestion protocol.
+func writeFrame(t *testing.T, w io.Writer, buf []byte) {
+	t.Helper()
+	frame := make([]byte, 4+len(buf))
+	binary.BigEndian.PutUint32(frame, uint32(len(buf)))
+	copy(frame[4:], buf)
+	if _, err := w.Write(frame); err != nil {
+		t.Fatalf("writing frame: %v", err)
+	}
+}
+
+// readReply returns the error code of the reply frame,
+// empty if it reports success.
+func readReply(t *testing.T, r io.Reader) string {
+	t.Helper()
+	buf, err := readFrame(r)
+	if err != nil {
+		t.Fatalf("reading reply: %v", err)
+	}
+	if len(buf) == 0 {
+		return ""
+	}
+	var reply struct{ Code string }
+	if err := json.Unmarshal(buf, &reply); err != nil {
+		t.Fatalf("decoding reply %q: %v", buf, err)
+	}
+	return reply.Code
+}
+
+func TestServeConn(t *testing.T) {
+	entry, err := proto.Marshal(&pb.Entry{Origin: "a", Events: []*pb.Event{{Name: "e", Value: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		cfg         func(c *config.Config)
+		fill        bool // whether all request slots are taken
+		frames      [][]byte
+		wantReplies []string
+	}{
+		{
+			name:        "inserted",
+			frames:      [][]byte{entry, entry},
+			wantReplies: []string{"", ""},
+		},
+		{
+			name:        "malformed",
+			frames:      [][]byte{{0xff}, entry},
+			wantReplies: []string{"malformed", ""},
+		},
+		{
+			name: "tenant",
+			cfg: func(c *config.Config) {
+				c.TenantConfig = config.TenantConfig{Source: "header", Header: "Tenant", Required: true}
+			},
+			frames:      [][]byte{[]byte("t"), entry},
+			wantReplies: []string{"", ""},
+		},
+		{
+			name: "no tenant",
+			cfg: func(c *config.Config) {
+				c.TenantConfig = config.TenantConfig{Source: "header", Header: "Tenant", Required: true}
+			},
+			frames:      [][]byte{nil},
+			wantReplies: []string{"unauthenticated"},
+		},
+		{
+			name: "too many requests",
+			cfg: func(c *config.Config) {
+				c.MaxInFlight = 1
+			},
+			fill:        true,
+			frames:      [][]byte{entry},
+			wantReplies: []string{"resource_exhausted"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			s := newTestServer(t, cfg, newFakeSession())
+			if tt.fill {
+				s.limiter.slots <- struct{}{}
+			}
+			client, conn := net.Pipe()
+			defer client.Close()
+			go s.serveConn(conn, time.Minute)
+
+			for i, f := range tt.frames {
+				writeFrame(t, client, f)
+				if code := readReply(t, client); code != tt.wantReplies[i] {
+					t.Errorf("reply to frame %d = %q, want %q", i, code, tt.wantReplies[i])
+				}
+			}
+		})
+	}
+}
+
+func TestServeConnIdleTimeout(t *testing.T) {
+	s := newTestServer(t, testConfig(), newFakeSession())
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		s.serveConn(conn, 10*time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("idle connection is not closed")
+	}
+}
+
+// flakyListener fails to accept with the errors, then
+// returns errClosed.
+type flakyListener struct {
+	net.Listener
+	errs []error
+}
+
+var errClosed = errors.New("closed")
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if len(l.errs) == 0 {
+		return nil, errClosed
+	}
+	err := l.errs[0]
+	l.errs = l.errs[1:]
+	return nil, err
+}
+
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "too many open files" }
+func (temporaryError) Timeout() bool   { return false }
+func (temporaryError) Temporary() bool { return true }
+
+func TestServeTCPTemporaryErrors(t *testing.T) {
+	s := newTestServer(t, testConfig(), newFakeSession())
+	l := &flakyListener{errs: []error{temporaryError{}, temporaryError{}}}
+	if err := s.ServeTCP(l, 0, 0); err != errClosed {
+		t.Errorf("ServeTCP() = %v, want %v", err, errClosed)
+	}
+	if len(l.errs) > 0 {
+		t.Errorf("ServeTCP() returned before retrying %d temporary errors", len(l.errs))
+	}
+}
+
+func TestServeTCPMaxConns(t *testing.T) {
+	const maxConns = 2
+	entry, err := proto.Marshal(&pb.Entry{Origin: "a", Events: []*pb.Event{{Name: "e", Value: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t, testConfig(), newFakeSession())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go s.ServeTCP(l, maxConns, 0)
+
+	for i := 0; i < maxConns; i++ {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		// The connection is served once the entry is inserted.
+		writeFrame(t, conn, entry)
+		if code := readReply(t, conn); code != "" {
+			t.Fatalf("reply on connection %d = %q, want success", i, code)
+		}
+	}
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if code := readReply(t, conn); code != "resource_exhausted" {
+		t.Errorf("reply on connection %d = %q, want %q", maxConns, code, "resource_exhausted")
+	}
+	if _, err := readFrame(conn); err != io.EOF {
+		t.Errorf("reading the rejected connection = %v, want %v", err, io.EOF)
+	}
+	if got := s.metrics.activeConns.Value(); got != maxConns {
+		t.Errorf("tcp_active_conns = %d, want %d", got, maxConns)
+	}
+	if got := s.metrics.rejectedConns.Value(); got != 1 {
+		t.Errorf("tcp_rejected_conns = %d, want 1", got)
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/mykodev/myko/config"
+	"github.com/twitchtv/twirp"
+)
+
+type tenantKey struct{}
+
+// WithTenant wraps the handler to resolve the tenant of each
+// request from the configured source and make it available with
+// TenantFromContext. Requests without a tenant are rejected if
+// one is required.
+func WithTenant(h http.Handler, cfg config.TenantConfig) (http.Handler, error) {
+	resolve, err := newTenantResolver(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if resolve == nil {
+		return h, nil
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := resolve(r.Header.Get(cfg.Header))
+		if tenant == "" {
+			if cfg.Required {
+				twirp.WriteError(w, errNoTenant)
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(withTenant(r.Context(), tenant)))
+	}), nil
+}
+
+var errNoTenant = twirp.NewError(twirp.Unauthenticated, "no tenant")
+
+// tenantResolver returns the tenant identified by the
+// value of the tenant header, empty if there is none.
+type tenantResolver func(v string) string
+
+// newTenantResolver returns the resolver of the configured
+// source, nil if tenants are disabled.
+func newTenantResolver(cfg config.TenantConfig) (tenantResolver, error) {
+	var resolve tenantResolver
+	switch cfg.Source {
+	case "":
+		return nil, nil
+	case "header":
+		resolve = func(v string) string {
+			return v
+		}
+	case "api_key":
+		resolve = func(v string) string {
+			if v == "" {
+				return ""
+			}
+			return cfg.APIKeys[v]
+		}
+	default:
+		return nil, fmt.Errorf("unknown tenant source %q", cfg.Source)
+	}
+	if cfg.Header == "" {
+		return nil, fmt.Errorf("no header given for tenant source %q", cfg.Source)
+	}
+	return resolve, nil
+}
+
+func withTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant resolved by WithTenant,
+// or empty if the request has none.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
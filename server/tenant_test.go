@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mykodev/myko/config"
+)
+
+func TestWithTenant(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.TenantConfig
+		header     string
+		wantTenant string
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "disabled", header: "t", wantStatus: http.StatusOK},
+		{
+			name:       "header",
+			cfg:        config.TenantConfig{Source: "header", Header: "Tenant"},
+			header:     "t",
+			wantTenant: "t",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "api key",
+			cfg:        config.TenantConfig{Source: "api_key", Header: "Key", APIKeys: map[string]string{"k": "t"}},
+			header:     "k",
+			wantTenant: "t",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown api key",
+			cfg:        config.TenantConfig{Source: "api_key", Header: "Key", APIKeys: map[string]string{"k": "t"}},
+			header:     "x",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "required",
+			cfg:        config.TenantConfig{Source: "header", Header: "Tenant", Required: true},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{name: "cert", cfg: config.TenantConfig{Source: "cert"}, wantErr: true},
+		{name: "no header", cfg: config.TenantConfig{Source: "header"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tenant string
+			h, err := WithTenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenant = TenantFromContext(r.Context())
+			}), tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithTenant() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.cfg.Header != "" {
+				r.Header.Set(tt.cfg.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tenant != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", tenant, tt.wantTenant)
+			}
+		})
+	}
+}
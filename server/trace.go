@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/mykodev/myko/datastore/cassandra"
+	"github.com/mykodev/myko/format"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// traceStmt returns the statement selecting the rows
+// of a trace restricted by the WHERE clause.
+func (s *Server) traceStmt(where string) string {
+	limit := ""
+	if s.maxTrace > 0 {
+		// Selecting one more row than the limit is
+		// enough to tell whether it is exceeded.
+		limit = fmt.Sprintf(" LIMIT %d", s.maxTrace+1)
+	}
+	return `
+		SELECT id, origin, event, value, int_value, unit, created_at
+		FROM {{.Keyspace}}.events ` + where + limit + ` ALLOW FILTERING`
+}
+
+// QueryTrace returns the events of a trace ordered by their
+// creation time without aggregating them.
+func (s *Server) QueryTrace(ctx context.Context, req *pb.QueryTraceRequest) (*pb.QueryTraceResponse, error) {
+	if req.TraceId == "" {
+		return nil, twirp.RequiredArgumentError("trace_id")
+	}
+	traceIDs := []string{req.TraceId}
+	if legacy := format.Escape(req.TraceId); s.legacyKeys && legacy != req.TraceId {
+		traceIDs = append(traceIDs, legacy)
+	}
+
+	var events []*pb.TraceEvent
+	for _, traceID := range traceIDs {
+		e, err := s.traceEvents(ctx, traceID)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e...)
+		if s.maxTrace > 0 && len(events) > s.maxTrace {
+			return nil, twirp.NewErrorf(twirp.FailedPrecondition,
+				"trace has more than %d events", s.maxTrace)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.AsTime().Before(events[j].CreatedAt.AsTime())
+	})
+	return &pb.QueryTraceResponse{Events: events}, nil
+}
+
+// traceEvents returns the events stored with the trace id.
+func (s *Server) traceEvents(ctx context.Context, traceID string) ([]*pb.TraceEvent, error) {
+	filter := cassandra.Filter{TraceID: traceID}
+	where, vals, err := filter.CQL()
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := s.reads.Query(s.traceStmt(where), vals...)
+	if err != nil {
+		return nil, err
+	}
+	q = q.WithContext(ctx)
+
+	var (
+		id        gocql.UUID
+		origin    string
+		name      string
+		unit      string
+		value     float64
+		intValue  *int64
+		createdAt time.Time
+		events    []*pb.TraceEvent
+	)
+	iter := q.Iter()
+	for iter.Scan(&id, &origin, &name, &value, &intValue, &unit, &createdAt) {
+		events = append(events, &pb.TraceEvent{
+			Id:     id.String(),
+			Origin: origin,
+			Event: &pb.Event{
+				Name:     s.eventName(name),
+				Unit:     unit,
+				Value:    value,
+				IntValue: intValue,
+			},
+			CreatedAt: timestamppb.New(createdAt),
+		})
+		intValue = nil
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+type traceCtxKey struct{}
+
+func TestQueryTrace(t *testing.T) {
+	first, second, legacy := gocql.MustRandomUUID(), gocql.MustRandomUUID(), gocql.MustRandomUUID()
+	rows := map[string][][]interface{}{
+		"a:b": {
+			{second, "o", "b", 2.0, nil, "", time.Unix(20, 0)},
+			{first, "o", "a", 1.0, nil, "", time.Unix(10, 0)},
+		},
+		"a_b": {
+			{legacy, "o", "c", 3.0, nil, "", time.Unix(15, 0)},
+		},
+	}
+	tests := []struct {
+		name       string
+		legacyKeys bool
+		max        int
+		wantCode   twirp.ErrorCode
+		want       []gocql.UUID
+	}{
+		{name: "ordered", want: []gocql.UUID{first, second}},
+		{name: "legacy keys", legacyKeys: true, want: []gocql.UUID{first, legacy, second}},
+		{name: "at the limit", max: 2, want: []gocql.UUID{first, second}},
+		{name: "over the limit", max: 1, wantCode: twirp.FailedPrecondition},
+		{name: "legacy over the limit", legacyKeys: true, max: 2, wantCode: twirp.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession()
+			session.handle(func(q *fakeQuery) ([][]interface{}, error) {
+				return rows[q.vals[0].(string)], nil
+			})
+			cfg := testConfig()
+			cfg.QueryConfig.LegacyKeys = tt.legacyKeys
+			cfg.QueryConfig.MaxTraceEvents = tt.max
+			s := newTestServer(t, cfg, session)
+
+			ctx := context.WithValue(context.Background(), traceCtxKey{}, "v")
+			resp, err := s.QueryTrace(ctx, &pb.QueryTraceRequest{TraceId: "a:b"})
+			if code := errorCode(err); code != tt.wantCode || (code == "" && err != nil) {
+				t.Fatalf("QueryTrace() = %v, want code %q", err, tt.wantCode)
+			}
+			for _, q := range session.ran("SELECT") {
+				if q.ctx == nil || q.ctx.Value(traceCtxKey{}) != "v" {
+					t.Error("QueryTrace() ran a query without the request context")
+				}
+			}
+			if err != nil {
+				return
+			}
+			if len(resp.Events) != len(tt.want) {
+				t.Fatalf("QueryTrace() returned %d events, want %d", len(resp.Events), len(tt.want))
+			}
+			for i, e := range resp.Events {
+				if e.Id != tt.want[i].String() {
+					t.Errorf("QueryTrace() event %d id = %q, want %q", i, e.Id, tt.want[i])
+				}
+			}
+		})
+	}
+}
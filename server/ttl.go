@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+)
+
+// eventTTLs resolves the TTL of the events by their names.
+type eventTTLs struct {
+	ttl      time.Duration
+	exact    map[string]time.Duration
+	patterns []string // most specific first
+	globs    map[string]time.Duration
+}
+
+func newEventTTLs(ttl time.Duration, overrides map[string]time.Duration) (*eventTTLs, error) {
+	t := &eventTTLs{
+		ttl:   ttl,
+		exact: make(map[string]time.Duration),
+		globs: make(map[string]time.Duration),
+	}
+	for pattern, d := range overrides {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid event TTL pattern %q: %v", pattern, err)
+		}
+		if d < time.Second && d != 0 {
+			return nil, fmt.Errorf("event TTL of %q is less than a second", pattern)
+		}
+		if !hasMeta(pattern) {
+			t.exact[pattern] = d
+			continue
+		}
+		t.globs[pattern] = d
+		t.patterns = append(t.patterns, pattern)
+	}
+	sort.Slice(t.patterns, func(i, j int) bool {
+		if len(t.patterns[i]) != len(t.patterns[j]) {
+			return len(t.patterns[i]) > len(t.patterns[j])
+		}
+		return t.patterns[i] < t.patterns[j]
+	})
+	return t, nil
+}
+
+// ttlOf returns the TTL of the event name. Zero means
+// the events don't expire.
+func (t *eventTTLs) ttlOf(name string) time.Duration {
+	if d, ok := t.exact[name]; ok {
+		return d
+	}
+	for _, p := range t.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return t.globs[p]
+		}
+	}
+	return t.ttl
+}
+
+// shortest returns the shortest non-zero TTL of the event names,
+// or of all the events if there are no names. Zero means none
+// of the events expire.
+func (t *eventTTLs) shortest(names []string) time.Duration {
+	ttls := make([]time.Duration, 0, len(names))
+	for _, name := range names {
+		ttls = append(ttls, t.ttlOf(name))
+	}
+	if len(names) == 0 {
+		ttls = append(ttls, t.ttl)
+		for _, d := range t.exact {
+			ttls = append(ttls, d)
+		}
+		for _, d := range t.globs {
+			ttls = append(ttls, d)
+		}
+	}
+	var min time.Duration
+	for _, d := range ttls {
+		if d > 0 && (min == 0 || d < min) {
+			min = d
+		}
+	}
+	return min
+}
+
+func hasMeta(pattern string) bool {
+	for _, r := range pattern {
+		switch r {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
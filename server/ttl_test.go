@@ -0,0 +1,85 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+var testEventTTLs = map[string]time.Duration{
+	"http.requests": time.Hour,
+	"http.*":        2 * time.Hour,
+	"http.req*":     3 * time.Hour,
+	"h*":            4 * time.Hour,
+	"debug":         0, // never expires
+}
+
+func TestEventTTLs(t *testing.T) {
+	ttls, err := newEventTTLs(24*time.Hour, testEventTTLs)
+	if err != nil {
+		t.Fatalf("newEventTTLs() = %v", err)
+	}
+	tests := []struct {
+		name string
+		want time.Duration
+	}{
+		{name: "http.requests", want: time.Hour},         // exact name
+		{name: "http.request_size", want: 3 * time.Hour}, // longer glob
+		{name: "http.errors", want: 2 * time.Hour},
+		{name: "hosts", want: 4 * time.Hour}, // shorter glob
+		{name: "disk", want: 24 * time.Hour}, // global
+		{name: "debug", want: 0},
+	}
+	for _, tt := range tests {
+		if got := ttls.ttlOf(tt.name); got != tt.want {
+			t.Errorf("ttlOf(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, overrides := range []map[string]time.Duration{
+		{"[": time.Hour},
+		{"e": time.Millisecond},
+	} {
+		if _, err := newEventTTLs(0, overrides); err == nil {
+			t.Errorf("newEventTTLs(%v) = nil error, want an error", overrides)
+		}
+	}
+}
+
+func TestFlushEventTTLs(t *testing.T) {
+	session := newFakeSession()
+	cfg := testConfig()
+	cfg.DataConfig.CassandraConfig.TTL = 24 * time.Hour
+	cfg.DataConfig.CassandraConfig.EventTTLs = testEventTTLs
+	s := newTestServer(t, cfg, session)
+
+	// The first write flushes, nothing was flushed before.
+	err := s.batchWriter.Write(&pb.Entry{Origin: "o", Events: []*pb.Event{
+		{Name: "http.requests", Value: 1},
+		{Name: "http.request_size", Value: 1},
+		{Name: "disk", Value: 1},
+		{Name: "debug", Value: 1},
+	}})
+	if err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	want := map[string]int64{ // seconds
+		"http.requests":     3600,
+		"http.request_size": 3 * 3600,
+		"disk":              24 * 3600,
+		"debug":             0,
+	}
+	got := make(map[string]int64)
+	for _, st := range session.executed("INSERT") {
+		got[st.vals[3].(string)] = st.vals[8].(int64)
+	}
+	for name, ttl := range want {
+		if got[name] != ttl {
+			t.Errorf("flush bound TTL %d to %q, want %d", got[name], name, ttl)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("flush inserted %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import "sync"
+
+// overflowUnit is the unit events are recorded with
+// once their name exceeds the distinct unit limit.
+const overflowUnit = "unknown"
+
+// unitLimiter limits the number of distinct units
+// an event name may be recorded with.
+type unitLimiter struct {
+	mu    sync.Mutex
+	max   int
+	units map[string]map[string]struct{} // name -> units
+}
+
+func newUnitLimiter(max int) *unitLimiter {
+	return &unitLimiter{
+		max:   max,
+		units: make(map[string]map[string]struct{}),
+	}
+}
+
+// admit records the unit for the event name and reports
+// whether the name is still within the distinct unit limit.
+func (l *unitLimiter) admit(name, unit string) bool {
+	if unit == overflowUnit {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	units, ok := l.units[name]
+	if !ok {
+		units = make(map[string]struct{})
+		l.units[name] = units
+	}
+	if _, ok := units[unit]; ok {
+		return true
+	}
+	if len(units) >= l.max {
+		return false
+	}
+	units[unit] = struct{}{}
+	return true
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestLimitUnits(t *testing.T) {
+	inserted := []*pb.Event{ // one per request
+		{Name: "e", Unit: "ms"},
+		{Name: "e", Unit: "s"},
+		{Name: "e", Unit: "min"},
+		{Name: "f", Unit: "min"},
+		{Name: "e", Unit: "ms"},
+		{Name: "e", Unit: overflowUnit},
+	}
+	tests := []struct {
+		name           string
+		max            int
+		reject         bool
+		wantUnits      []string // recorded for the inserted events, empty if rejected
+		wantViolations int64
+	}{
+		{name: "disabled", wantUnits: []string{"ms", "s", "min", "min", "ms", overflowUnit}},
+		{name: "under the cap", max: 3, wantUnits: []string{"ms", "s", "min", "min", "ms", overflowUnit}},
+		{
+			name:           "unknown",
+			max:            2,
+			wantUnits:      []string{"ms", "s", overflowUnit, "min", "ms", overflowUnit},
+			wantViolations: 1,
+		},
+		{
+			name:           "rejected",
+			max:            2,
+			reject:         true,
+			wantUnits:      []string{"ms", "s", "", "min", "ms", overflowUnit},
+			wantViolations: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.IngestConfig.MaxUnitsPerEvent = tt.max
+			cfg.IngestConfig.RejectUnitOverflow = tt.reject
+			s := newTestServer(t, cfg, newFakeSession())
+
+			var units []string
+			for _, e := range inserted {
+				event := &pb.Event{Name: e.Name, Unit: e.Unit}
+				err := s.limitUnits([]*pb.Entry{{Origin: "o", Events: []*pb.Event{event}}})
+				if err != nil {
+					if code := errorCode(err); code != twirp.InvalidArgument {
+						t.Errorf("limitUnits(%v) = %v, want InvalidArgument", e, err)
+					}
+					units = append(units, "")
+					continue
+				}
+				units = append(units, event.Unit)
+			}
+			if !reflect.DeepEqual(units, tt.wantUnits) {
+				t.Errorf("limitUnits() recorded %q, want %q", units, tt.wantUnits)
+			}
+			if got := s.metrics.unitLimitViolations.Value(); got != tt.wantViolations {
+				t.Errorf("unit_limit_violations = %d, want %d", got, tt.wantViolations)
+			}
+		})
+	}
+}
+
+func TestDefaultUnits(t *testing.T) {
+	tests := []struct {
+		name        string
+		require     bool
+		entries     []*pb.Entry
+		wantCode    twirp.ErrorCode
+		wantBuffers map[string]float64 // by origin/event/unit
+	}{
+		{
+			name: "defaulted",
+			entries: []*pb.Entry{
+				{Origin: "o", Events: []*pb.Event{{Name: "latency", Value: 1}, {Name: "latency", Unit: "ms", Value: 2}}},
+				{Origin: "o", Events: []*pb.Event{{Name: "latency", Unit: "s", Value: 3}, {Name: "size", Value: 4}}},
+				{Origin: "p", Events: []*pb.Event{{Name: "latency", Value: 5}}},
+			},
+			wantBuffers: map[string]float64{
+				"o/latency/ms": 3, // aggregated with the explicit unit
+				"o/latency/s":  3,
+				"o/size/":      4,
+				"p/latency/":   5,
+			},
+		},
+		{
+			name:        "required",
+			require:     true,
+			entries:     []*pb.Entry{{Origin: "o", Events: []*pb.Event{{Name: "latency", Value: 1}, {Name: "size", Unit: "B", Value: 2}}}},
+			wantBuffers: map[string]float64{"o/latency/ms": 1, "o/size/B": 2},
+		},
+		{
+			name:     "missing",
+			require:  true,
+			entries:  []*pb.Entry{{Origin: "o", Events: []*pb.Event{{Name: "size", Value: 1}}}},
+			wantCode: twirp.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.IngestConfig.DefaultUnits = map[string]map[string]string{"o": {"latency": "ms"}}
+			cfg.IngestConfig.RequireUnits = tt.require
+			s := newTestServer(t, cfg, newFakeSession())
+			s.batchWriter.lastExport = time.Now() // nothing is due to be flushed
+
+			_, err := s.InsertEvents(context.Background(), &pb.InsertEventsRequest{Entries: tt.entries})
+			if errorCode(err) != tt.wantCode || (tt.wantCode == "" && err != nil) {
+				t.Fatalf("InsertEvents() = %v, want code %q", err, tt.wantCode)
+			}
+			buffers := make(map[string]float64)
+			for k, e := range s.batchWriter.events {
+				origin, _, name, unit, _ := parseKey(k)
+				buffers[origin+"/"+name+"/"+unit] = e.Value
+			}
+			if tt.wantBuffers == nil {
+				tt.wantBuffers = map[string]float64{}
+			}
+			if !reflect.DeepEqual(buffers, tt.wantBuffers) {
+				t.Errorf("InsertEvents() buffered %v, want %v", buffers, tt.wantBuffers)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/twitchtv/twirp"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+// errIntOverflow is returned if the sum of integer values
+// doesn't fit in an int64.
+var errIntOverflow = twirp.NewError(twirp.OutOfRange, "integer value overflows int64")
+
+// addValue adds the values and the count of src to dst.
+// Integer values are summed in integer arithmetic, dst is
+// left unchanged if their sum overflows.
+func addValue(dst, src *pb.Event) error {
+	if src.IntValue != nil {
+		a, b := dst.GetIntValue(), *src.IntValue
+		sum := a + b
+		if (b > 0 && sum < a) || (b < 0 && sum > a) {
+			return errIntOverflow
+		}
+		dst.IntValue = &sum
+	}
+	dst.Value += src.Value
+	dst.Count += src.Count
+	return nil
+}
+
+// totalValue returns the sum of the float and integer values of e.
+func totalValue(e *pb.Event) float64 {
+	return e.Value + float64(e.GetIntValue())
+}
+
+// limitValues rejects the inserted values that are NaN or
+// infinite, and rejects or clamps the ones whose magnitude
+// exceeds the configured maximum.
+func (s *Server) limitValues(entries []*pb.Entry) error {
+	for _, entry := range entries {
+		for _, event := range entry.Events {
+			if math.IsNaN(event.Value) || math.IsInf(event.Value, 0) {
+				s.metrics.valueLimitViolations.Add(1)
+				return twirp.InvalidArgumentError("value", fmt.Sprintf("value of event %q is %g", event.Name, event.Value))
+			}
+			if s.maxAbsValue <= 0 {
+				continue
+			}
+			overflow := math.Abs(event.Value) > s.maxAbsValue
+			if event.IntValue != nil && math.Abs(float64(*event.IntValue)) > s.maxAbsValue {
+				overflow = true
+			}
+			if !overflow {
+				continue
+			}
+			s.metrics.valueLimitViolations.Add(1)
+			if !s.clampValues {
+				return twirp.InvalidArgumentError("value", fmt.Sprintf("value of event %q exceeds %g in magnitude", event.Name, s.maxAbsValue))
+			}
+			if math.Abs(event.Value) > s.maxAbsValue {
+				event.Value = math.Copysign(s.maxAbsValue, event.Value)
+			}
+			if event.IntValue != nil && math.Abs(float64(*event.IntValue)) > s.maxAbsValue {
+				v := int64(math.Copysign(math.Floor(s.maxAbsValue), float64(*event.IntValue)))
+				event.IntValue = &v
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mykodev/myko/proto"
+)
+
+func TestLimitValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      float64
+		clamp    bool
+		value    float64
+		wantCode twirp.ErrorCode
+		want     float64
+	}{
+		{name: "no limit", value: 1e300, want: 1e300},
+		{name: "nan without limit", value: math.NaN(), wantCode: twirp.InvalidArgument},
+		{name: "inf without limit", value: math.Inf(1), wantCode: twirp.InvalidArgument},
+		{name: "-inf clamped", max: 10, clamp: true, value: math.Inf(-1), wantCode: twirp.InvalidArgument},
+		{name: "within limit", max: 10, value: -10, want: -10},
+		{name: "exceeds limit", max: 10, value: 11, wantCode: twirp.InvalidArgument},
+		{name: "clamped", max: 10, clamp: true, value: -11, want: -10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.IngestConfig.MaxAbsValue = tt.max
+			cfg.IngestConfig.ClampValues = tt.clamp
+			s := newTestServer(t, cfg, newFakeSession())
+
+			event := &pb.Event{Name: "e", Value: tt.value}
+			err := s.limitValues([]*pb.Entry{{Events: []*pb.Event{event}}})
+			if code := errorCode(err); code != tt.wantCode || (code == "" && err != nil) {
+				t.Fatalf("limitValues() = %v, want code %q", err, tt.wantCode)
+			}
+			if err == nil && event.Value != tt.want {
+				t.Errorf("limitValues() left %g, want %g", event.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		dst     *int64
+		src     *int64
+		want    *int64
+		wantErr bool
+	}{
+		{name: "no ints"},
+		{name: "sum", dst: int64p(2), src: int64p(-3), want: int64p(-1)},
+		{name: "into nil", src: int64p(math.MaxInt64), want: int64p(math.MaxInt64)},
+		{name: "overflow", dst: int64p(math.MaxInt64), src: int64p(1), want: int64p(math.MaxInt64), wantErr: true},
+		{name: "underflow", dst: int64p(math.MinInt64), src: int64p(-1), want: int64p(math.MinInt64), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := &pb.Event{Value: 1, IntValue: tt.dst, Count: 1}
+			err := addValue(dst, &pb.Event{Value: 2, IntValue: tt.src, Count: 1})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addValue() = %v, want error %v", err, tt.wantErr)
+			}
+			if (dst.IntValue == nil) != (tt.want == nil) || (tt.want != nil && *dst.IntValue != *tt.want) {
+				t.Errorf("addValue() int value = %v, want %v", dst.IntValue, tt.want)
+			}
+			wantValue, wantCount := 3.0, int64(2)
+			if tt.wantErr {
+				wantValue, wantCount = 1, 1 // unchanged
+			}
+			if dst.Value != wantValue || dst.Count != wantCount {
+				t.Errorf("addValue() = value %g count %d, want %g and %d", dst.Value, dst.Count, wantValue, wantCount)
+			}
+		})
+	}
+}
+
+func TestAddEventsOverflow(t *testing.T) {
+	events := make(map[string]*pb.Event)
+	entry := func(v int64) *pb.Entry {
+		return &pb.Entry{Origin: "o", Events: []*pb.Event{
+			{Name: "a", IntValue: int64p(1), Count: 1},
+			{Name: "b", IntValue: int64p(v), Count: 1},
+		}}
+	}
+	if err := addEvents(events, entry(math.MaxInt64), time.Time{}); err != nil {
+		t.Fatalf("addEvents() = %v", err)
+	}
+	if err := addEvents(events, entry(1), time.Time{}); err == nil {
+		t.Fatal("addEvents() = nil, want overflow error")
+	}
+	for _, e := range events {
+		if e.Count != 1 {
+			t.Errorf("addEvents() changed %q to count %d after failing", e.Name, e.Count)
+		}
+	}
+}
+
+func TestSubtractEvents(t *testing.T) {
+	events := func(es ...*pb.Event) map[string]*pb.Event {
+		v := make(map[string]*pb.Event, len(es))
+		for _, e := range es {
+			v[groupKey(e.Name, e.Unit)] = e
+		}
+		return v
+	}
+	tests := []struct {
+		name     string
+		v        map[string]*pb.Event
+		baseline map[string]*pb.Event
+		want     map[string]*pb.Event
+		wantErr  error
+	}{
+		{
+			name:     "both",
+			v:        events(&pb.Event{Name: "a", Value: 10, Count: 2}),
+			baseline: events(&pb.Event{Name: "a", Value: 3, Count: 1}),
+			want:     events(&pb.Event{Name: "a", Value: 7, Count: 2}),
+		},
+		{
+			name:     "int values",
+			v:        events(&pb.Event{Name: "a", IntValue: int64p(10)}, &pb.Event{Name: "b", Value: 1.5}),
+			baseline: events(&pb.Event{Name: "a", IntValue: int64p(4)}, &pb.Event{Name: "b", IntValue: int64p(1)}),
+			want:     events(&pb.Event{Name: "a", IntValue: int64p(6)}, &pb.Event{Name: "b", Value: 1.5, IntValue: int64p(-1)}),
+		},
+		{
+			name:     "only queried",
+			v:        events(&pb.Event{Name: "a", Value: 5}),
+			baseline: events(),
+			want:     events(&pb.Event{Name: "a", Value: 5}),
+		},
+		{
+			name:     "only baseline",
+			v:        events(),
+			baseline: events(&pb.Event{Name: "a", Value: 2, Unit: "ms"}, &pb.Event{Name: "b", IntValue: int64p(3)}),
+			want:     events(&pb.Event{Name: "a", Value: -2, Unit: "ms"}, &pb.Event{Name: "b", IntValue: int64p(-3)}),
+		},
+		{
+			name:     "overflow",
+			v:        events(&pb.Event{Name: "a", IntValue: int64p(0)}),
+			baseline: events(&pb.Event{Name: "a", IntValue: int64p(math.MinInt64)}),
+			wantErr:  errIntOverflow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := subtractEvents(tt.v, tt.baseline)
+			if err != tt.wantErr {
+				t.Fatalf("subtractEvents() = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(tt.v) != len(tt.want) {
+				t.Fatalf("subtractEvents() = %v, want %v", tt.v, tt.want)
+			}
+			for k, want := range tt.want {
+				if got := tt.v[k]; !proto.Equal(got, want) {
+					t.Errorf("subtractEvents() %s = %v, want %v", k, got, want)
+				}
+			}
+		})
+	}
+}